@@ -0,0 +1,46 @@
+// bnapi.go
+// Some structs and URLs for the Binance public WebSocket streams are here.
+// Only the 24 hour ticker stream is covered.
+
+package bnapi
+
+import "strings"
+
+// StreamURL is the URL for combined streams; stream names are appended,
+// separated by slashes.
+const StreamURL string = "wss://stream.binance.com:9443/stream?streams="
+
+// QuoteAssets is a mapping of target currencies to the quote asset which
+// stands in for them on Binance, where they differ.
+var QuoteAssets = map[string]string{
+	"USD": "USDT"}
+
+// SpotQuotes are the quote assets of Binance spot pairs which ccpc streams
+// against. Pairs in other quote assets do not exist on Binance.
+var SpotQuotes = []string{
+	"BNB", "BRL", "BTC", "DAI", "ETH", "EUR", "FDUSD", "TRY", "TUSD", "USDC", "USDT"}
+
+// StreamMessage is a struct for a message from a combined stream.
+type StreamMessage struct {
+	Stream string `json:"stream"`
+	Data   Ticker `json:"data"`
+}
+
+// Ticker is a struct for a 24 hour ticker stream event.
+// Binance uses single letter keys which differ only by case, so both cases
+// are declared to keep encoding/json from matching the wrong field.
+type Ticker struct {
+	EventType     string  `json:"e"`
+	EventTime     int64   `json:"E"`
+	Symbol        string  `json:"s"`
+	PriceChange   float64 `json:"p,string"`
+	PriceChangePc float64 `json:"P,string"`
+	Last          float64 `json:"c,string"`
+	CloseTime     int64   `json:"C"`
+	Volume        float64 `json:"v,string"`
+}
+
+// TickerStream returns the 24 hour ticker stream name for a trading pair.
+func TickerStream(base, quote string) string {
+	return strings.ToLower(base+quote) + "@ticker"
+}
@@ -0,0 +1,62 @@
+// cache.go
+// Responses which rarely change are cached in a ccpc directory under the
+// user's cache directory, e.g. ~/.cache/ccpc on Linux.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Returns the ccpc cache directory. It is not created.
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ccpc"), nil
+}
+
+// Decodes a cached JSON file into v, reporting whether it was written
+// within maxAge. A missing or unreadable cache is an error.
+func readCache(name string, maxAge time.Duration, v interface{}) (fresh bool, err error) {
+	defer func() {
+		logCache(name, fresh && err == nil)
+	}()
+	dir, err := cacheDir()
+	if err != nil {
+		return false, err
+	}
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, err
+	}
+	return time.Since(info.ModTime()) <= maxAge, nil
+}
+
+// Writes v to the cache as JSON, creating the cache directory if needed.
+func writeCache(name string, v interface{}) error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+}
@@ -0,0 +1,71 @@
+// cgCoinNames.go
+
+package cgapi
+
+// CGCoinNames is a hash table which contains coin ID keys and the
+// corresponding coin name values, where the name is known. Until the
+// tables are generated it holds only a few of the largest coins.
+var CGCoinNames = map[string]string{
+	"0x":                    "0x",
+	"algorand":              "Algorand",
+	"augur":                 "Augur",
+	"basic-attention-token": "Basic Attention Token",
+	"binancecoin":           "Binance Coin",
+	"bitcoin":               "Bitcoin",
+	"bitcoin-cash":          "Bitcoin Cash",
+	"bitcoin-cash-sv":       "Bitcoin SV",
+	"bitcoin-gold":          "Bitcoin Gold",
+	"bittorrent-2":          "BitTorrent",
+	"cardano":               "Cardano",
+	"chainlink":             "Chainlink",
+	"cosmos":                "Cosmos",
+	"crypto-com-chain":      "Crypto.com Coin",
+	"dai":                   "Dai",
+	"dash":                  "Dash",
+	"decred":                "Decred",
+	"digibyte":              "DigiByte",
+	"dogecoin":              "Dogecoin",
+	"enjincoin":             "Enjin Coin",
+	"eos":                   "EOS",
+	"ethereum":              "Ethereum",
+	"ethereum-classic":      "Ethereum Classic",
+	"hedera-hashgraph":      "Hedera Hashgraph",
+	"holotoken":             "Holo",
+	"huobi-token":           "Huobi Token",
+	"icon":                  "ICON",
+	"iota":                  "IOTA",
+	"komodo":                "Komodo",
+	"kyber-network":         "Kyber Network",
+	"lisk":                  "Lisk",
+	"litecoin":              "Litecoin",
+	"maker":                 "Maker",
+	"matic-network":         "Matic Network",
+	"monero":                "Monero",
+	"nano":                  "Nano",
+	"nem":                   "NEM",
+	"neo":                   "NEO",
+	"okb":                   "OKB",
+	"omisego":               "OMG Network",
+	"ontology":              "Ontology",
+	"paxos-standard":        "Paxos Standard",
+	"qtum":                  "Qtum",
+	"ravencoin":             "Ravencoin",
+	"ripple":                "XRP",
+	"siacoin":               "Siacoin",
+	"status":                "Status",
+	"steem":                 "Steem",
+	"stellar":               "Stellar",
+	"swissborg":             "SwissBorg",
+	"tether":                "Tether",
+	"tezos":                 "Tezos",
+	"theta-token":           "Theta Network",
+	"tron":                  "TRON",
+	"true-usd":              "TrueUSD",
+	"usd-coin":              "USD Coin",
+	"vechain":               "VeChain",
+	"verge":                 "Verge",
+	"waves":                 "Waves",
+	"wrapped-bitcoin":       "Wrapped Bitcoin",
+	"zcash":                 "Zcash",
+	"zilliqa":               "Zilliqa",
+}
@@ -0,0 +1,74 @@
+// cgTables.go
+
+package cgapi
+
+// TablesVersion is the date the embedded coin and currency tables were
+// fetched by generate.go, or "hand-maintained" until they are generated.
+const TablesVersion string = "hand-maintained"
+
+// SupportedCurrencies are the lower case target currencies supported by
+// the API when the tables were fetched.
+var SupportedCurrencies = []string{
+	"aed",
+	"ars",
+	"aud",
+	"bch",
+	"bdt",
+	"bhd",
+	"bits",
+	"bmd",
+	"bnb",
+	"brl",
+	"btc",
+	"cad",
+	"chf",
+	"clp",
+	"cny",
+	"czk",
+	"dkk",
+	"dot",
+	"eos",
+	"eth",
+	"eur",
+	"gbp",
+	"gel",
+	"hkd",
+	"huf",
+	"idr",
+	"ils",
+	"inr",
+	"jpy",
+	"krw",
+	"kwd",
+	"link",
+	"lkr",
+	"ltc",
+	"mmk",
+	"mxn",
+	"myr",
+	"ngn",
+	"nok",
+	"nzd",
+	"php",
+	"pkr",
+	"pln",
+	"rub",
+	"sar",
+	"sats",
+	"sek",
+	"sgd",
+	"thb",
+	"try",
+	"twd",
+	"uah",
+	"usd",
+	"vef",
+	"vnd",
+	"xag",
+	"xau",
+	"xdr",
+	"xlm",
+	"xrp",
+	"yfi",
+	"zar",
+}
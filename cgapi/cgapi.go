@@ -1,6 +1,9 @@
 // cgapi.go
 // Some structs and hash tables for the Coin Gecko API are here.
 // This is not exhaustive, and probably never will be.
+// The coin table and the supported currencies can be generated by generate.go.
+
+//go:generate go run generate.go
 
 package cgapi
 
@@ -12,121 +15,283 @@ type APIPing struct {
 	PingMsg string `json:"gecko_says"`
 }
 
+// CGSimplePriceURL is the API URL for prices of many coins in one request.
+// Query parameters: ids, vs_currencies, include_market_cap, include_24hr_vol,
+// include_24hr_change.
+const CGSimplePriceURL string = "https://api.coingecko.com/api/v3/simple/price"
+
+// CGSimplePrice maps coin IDs to their price data. Price data is keyed by
+// lower case target currency, e.g. "usd", "usd_market_cap", "usd_24h_vol"
+// and "usd_24h_change".
+type CGSimplePrice map[string]map[string]float64
+
+// CGSupportedCurrenciesURL is the API URL for the target currencies the API supports.
+const CGSupportedCurrenciesURL string = "https://api.coingecko.com/api/v3/simple/supported_vs_currencies"
+
+// CGSupportedCurrencies is a list of lower case currency abbreviations.
+type CGSupportedCurrencies []string
+
+// CGMarketsURL is the API URL for market data of many coins, ordered and paginated.
+// Query parameters: vs_currency, ids, order, per_page, page, price_change_percentage.
+const CGMarketsURL string = "https://api.coingecko.com/api/v3/coins/markets"
+
+// CGMarketsPerPage is the most coins CGMarketsURL returns per page.
+const CGMarketsPerPage int = 250
+
+// CGCoinMarket defines the market data of a coin in one target currency.
+// Price changes other than 24h are only present when requested with
+// price_change_percentage=1h,7d,30d.
+type CGCoinMarket struct {
+	ID                    string  `json:"id"`
+	Symbol                string  `json:"symbol"`
+	Name                  string  `json:"name"`
+	CurrentPrice          float64 `json:"current_price"`
+	MarketCap             float64 `json:"market_cap"`
+	MarketCapRank         int     `json:"market_cap_rank"`
+	FullyDilutedValuation float64 `json:"fully_diluted_valuation"`
+	TotalVolume           float64 `json:"total_volume"`
+	High24h               float64 `json:"high_24h"`
+	Low24h                float64 `json:"low_24h"`
+	PriceChange24h        float64 `json:"price_change_24h"`
+	PriceChange24hPc      float64 `json:"price_change_percentage_24h"`
+	PriceChange1hPc       float64 `json:"price_change_percentage_1h_in_currency"`
+	PriceChange7dPc       float64 `json:"price_change_percentage_7d_in_currency"`
+	PriceChange30dPc      float64 `json:"price_change_percentage_30d_in_currency"`
+	CirculatingSupply     float64 `json:"circulating_supply"`
+	TotalSupply           float64 `json:"total_supply"`
+	MaxSupply             float64 `json:"max_supply"`
+	Ath                   float64 `json:"ath"`
+	AthChangePc           float64 `json:"ath_change_percentage"`
+	LastUpdated           string  `json:"last_updated"`
+}
+
+// CGCoinListURL is the API URL for the ID, symbol and name of every coin.
+const CGCoinListURL string = "https://api.coingecko.com/api/v3/coins/list"
+
+// CGCoinList is a list of every coin the API knows.
+type CGCoinList []CGCoinListEntry
+
+// CGCoinListEntry defines a coin in the coin list.
+type CGCoinListEntry struct {
+	ID     string `json:"id"`
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+}
+
+// CGTrendingURL is the API URL for the coins most searched in the last 24 hours.
+const CGTrendingURL string = "https://api.coingecko.com/api/v3/search/trending"
+
+// CGTrending is a struct for the trending coins, most searched first.
+type CGTrending struct {
+	Coins []struct {
+		Item CGTrendingCoin `json:"item"`
+	} `json:"coins"`
+}
+
+// CGTrendingCoin defines a trending coin.
+type CGTrendingCoin struct {
+	ID            string `json:"id"`
+	Symbol        string `json:"symbol"`
+	Name          string `json:"name"`
+	MarketCapRank int    `json:"market_cap_rank"`
+	Score         int    `json:"score"`
+}
+
+// CGSearchURL is the API URL for searching coins by name or symbol.
+// Query parameters: query.
+const CGSearchURL string = "https://api.coingecko.com/api/v3/search"
+
+// CGSearch is a struct for the coins found by a search, best match first.
+// Exchanges and categories also exist in the JSON.
+type CGSearch struct {
+	Coins []CGSearchCoin `json:"coins"`
+}
+
+// CGSearchCoin defines a coin found by a search.
+type CGSearchCoin struct {
+	ID            string `json:"id"`
+	Symbol        string `json:"symbol"`
+	Name          string `json:"name"`
+	MarketCapRank int    `json:"market_cap_rank"`
+}
+
+// CGGlobalURL is the API URL for data on the whole cryptocurrency market.
+const CGGlobalURL string = "https://api.coingecko.com/api/v3/global"
+
+// CGGlobal is a struct for the global market data.
+type CGGlobal struct {
+	Data CGGlobalData `json:"data"`
+}
+
+// CGGlobalData encapsulates the global market data.
+// Maps are keyed by lower case currency or coin symbol. The market cap
+// change is of the market cap in USD.
+type CGGlobalData struct {
+	ActiveCryptocurrencies  int                `json:"active_cryptocurrencies"`
+	Markets                 int                `json:"markets"`
+	TotalMarketCap          map[string]float64 `json:"total_market_cap"`
+	TotalVolume             map[string]float64 `json:"total_volume"`
+	MarketCapPercentage     map[string]float64 `json:"market_cap_percentage"`
+	MarketCapChange24hUSDPc float64            `json:"market_cap_change_percentage_24h_usd"`
+	UpdatedAt               int64              `json:"updated_at"`
+}
+
 // MonetarySymbols is a mapping of currency abbreviations to symbols.
+// Fiat currencies follow ISO 4217; the live list of supported currencies
+// comes from CGSupportedCurrenciesURL.
 var MonetarySymbols = map[string]string{
-	"BTC": "btc",
-	"ETH": "eth",
-	"LTC": "ltc",
-	"BCH": "bch",
-	"BNB": "bnb",
-	"EOS": "eos",
-	"XRP": "xrp",
-	"XLM": "xlm",
-	"USD": "$",
-	"AED": "AED",
-	"ARS": "ARS$",
-	"AUD": "AUS$",
-	"BDT": "৳",
-	"BHD": "BHD",
-	"BMD": "BMD",
-	"BRL": "R$",
-	"CAD": "CAD$",
-	"CHF": "CHF",
-	"CLP": "CLP$",
-	"CNY": "元",
-	"CZK": "Kč",
-	"DKK": "kr",
-	"EUR": "€",
-	"GBP": "£",
-	"HKD": "HK$",
-	"HUF": "ft",
-	"IDR": "Rp",
-	"ILS": "₪",
-	"INR": "₹",
-	"JPY": "¥",
-	"KRW": "₩",
-	"KWD": "KWD",
-	"LKR": "Rs",
-	"MMK": "MMK",
-	"MXN": "MXN$",
-	"MYR": "RM",
-	"NOK": "kr",
-	"NZD": "NZD$",
-	"PHP": "₱",
-	"PKR": "Rs",
-	"PLN": "zł",
-	"RUB": "₽",
-	"SAR": "SAR",
-	"SEK": "kr",
-	"SGD": "SGD$",
-	"THB": "฿",
-	"TRY": "₺",
-	"TWD": "TWD",
-	"UAH": "₴",
-	"VEF": "Bs",
-	"VND": "₫",
-	"ZAR": "R$",
-	"XDR": "XDR",
-	"XAG": "XAG",
-	"XAU": "XAU"}
+	"BTC":  "btc",
+	"ETH":  "eth",
+	"LTC":  "ltc",
+	"BCH":  "bch",
+	"BNB":  "bnb",
+	"EOS":  "eos",
+	"XRP":  "xrp",
+	"XLM":  "xlm",
+	"LINK": "link",
+	"DOT":  "dot",
+	"YFI":  "yfi",
+	"BITS": "μBTC",
+	"SATS": "sats",
+	"USD":  "$",
+	"AED":  "AED",
+	"ARS":  "ARS$",
+	"AUD":  "AUS$",
+	"BDT":  "৳",
+	"BHD":  "BHD",
+	"BMD":  "BMD",
+	"BRL":  "R$",
+	"CAD":  "CAD$",
+	"CHF":  "CHF",
+	"CLP":  "CLP$",
+	"CNY":  "元",
+	"CZK":  "Kč",
+	"DKK":  "kr",
+	"EUR":  "€",
+	"GEL":  "₾",
+	"GBP":  "£",
+	"HKD":  "HK$",
+	"HUF":  "Ft",
+	"IDR":  "Rp",
+	"ILS":  "₪",
+	"INR":  "₹",
+	"JPY":  "¥",
+	"KRW":  "₩",
+	"KWD":  "KWD",
+	"LKR":  "Rs",
+	"MMK":  "MMK",
+	"MXN":  "MXN$",
+	"MYR":  "RM",
+	"NGN":  "₦",
+	"NOK":  "kr",
+	"NZD":  "NZD$",
+	"PHP":  "₱",
+	"PKR":  "Rs",
+	"PLN":  "zł",
+	"RUB":  "₽",
+	"SAR":  "SAR",
+	"SEK":  "kr",
+	"SGD":  "SGD$",
+	"THB":  "฿",
+	"TRY":  "₺",
+	"TWD":  "NT$",
+	"UAH":  "₴",
+	"VEF":  "Bs",
+	"VND":  "₫",
+	"ZAR":  "R",
+	"XDR":  "XDR",
+	"XAG":  "XAG",
+	"XAU":  "XAU"}
+
+// MonetaryDecimals is a mapping of currency abbreviations to the decimals
+// their prices are shown with, where that is not two.
+var MonetaryDecimals = map[string]int{
+	"BTC":  8,
+	"ETH":  6,
+	"LTC":  8,
+	"BCH":  8,
+	"BNB":  6,
+	"EOS":  4,
+	"XRP":  6,
+	"XLM":  7,
+	"LINK": 4,
+	"DOT":  4,
+	"YFI":  8,
+	"SATS": 0,
+	"BHD":  3,
+	"CLP":  0,
+	"IDR":  0,
+	"JPY":  0,
+	"KRW":  0,
+	"KWD":  3,
+	"VND":  0,
+	"XAG":  4,
+	"XAU":  6}
 
 // MonetaryNames is a mapping of currency abbreviations to names.
 var MonetaryNames = map[string]string{
-	"BTC": "Bitcoin",
-	"ETH": "Etherium",
-	"LTC": "Litecoin",
-	"BCH": "Bitcoin Cash",
-	"BNB": "Binance Coin",
-	"EOS": "EOS",
-	"XRP": "XRP",
-	"XLM": "Stellar Lumens",
-	"USD": "United States Dollar",
-	"AED": "United Arab Emirates Dirham",
-	"ARS": "Argentine Peso",
-	"AUD": "Australian Dollar",
-	"BDT": "Bangladeshi Taka",
-	"BHD": "Bahrain Dinar",
-	"BMD": "Bermuda Dollar",
-	"BRL": "Brazilian Real",
-	"CAD": "Canadian Dollar",
-	"CHF": "Swiss Franc",
-	"CLP": "Chilean Peso",
-	"CNY": "Chinese Yuan",
-	"CZK": "Czech Koruna",
-	"DKK": "Danish Krone",
-	"EUR": "Euro",
-	"GBP": "United Kingdom Pound Sterling",
-	"HKD": "Hong Kong Dollar",
-	"HUF": "Hungarian Forint",
-	"IDR": "Indonesian Rupiah",
-	"ILS": "Israeli Shekel",
-	"INR": "Indian Rupee",
-	"JPY": "Japanese Yen",
-	"KRW": "South Korean Won",
-	"KWD": "Kuwait Dinar",
-	"LKR": "Sri Lankan Rupee",
-	"MMK": "Myanmar Kyat",
-	"MXN": "Mexican Peso",
-	"MYR": "Malaysian Ringgit",
-	"NOK": "Norwegian Krone",
-	"NZD": "New Zealand Dollar",
-	"PHP": "Philippine Peso",
-	"PKR": "Pakistan Rupee",
-	"PLN": "Polish Złoty",
-	"RUB": "Russian Ruble",
-	"SAR": "Saudi Arabian Riyal",
-	"SEK": "Swedish Krona",
-	"SGD": "Singapore Dollar",
-	"THB": "Thai Baht",
-	"TRY": "Turkish Lira",
-	"TWD": "Taiwan Dollar",
-	"UAH": "Ukrainian Hryvna",
-	"VEF": "Venezuelan Bolívar",
-	"VND": "Vietnamese Dong",
-	"ZAR": "South African Rand",
-	"XDR": "IMF Special Drawing Rights",
-	"XAG": "Silver Ounce",
-	"XAU": "Gold Ounce"}
+	"BTC":  "Bitcoin",
+	"ETH":  "Ethereum",
+	"LTC":  "Litecoin",
+	"BCH":  "Bitcoin Cash",
+	"BNB":  "Binance Coin",
+	"EOS":  "EOS",
+	"XRP":  "XRP",
+	"XLM":  "Stellar Lumens",
+	"LINK": "Chainlink",
+	"DOT":  "Polkadot",
+	"YFI":  "yearn.finance",
+	"BITS": "Bits",
+	"SATS": "Satoshi",
+	"USD":  "United States Dollar",
+	"AED":  "United Arab Emirates Dirham",
+	"ARS":  "Argentine Peso",
+	"AUD":  "Australian Dollar",
+	"BDT":  "Bangladeshi Taka",
+	"BHD":  "Bahraini Dinar",
+	"BMD":  "Bermudian Dollar",
+	"BRL":  "Brazilian Real",
+	"CAD":  "Canadian Dollar",
+	"CHF":  "Swiss Franc",
+	"CLP":  "Chilean Peso",
+	"CNY":  "Chinese Yuan Renminbi",
+	"CZK":  "Czech Koruna",
+	"DKK":  "Danish Krone",
+	"EUR":  "Euro",
+	"GEL":  "Georgian Lari",
+	"GBP":  "Pound Sterling",
+	"HKD":  "Hong Kong Dollar",
+	"HUF":  "Hungarian Forint",
+	"IDR":  "Indonesian Rupiah",
+	"ILS":  "Israeli New Shekel",
+	"INR":  "Indian Rupee",
+	"JPY":  "Japanese Yen",
+	"KRW":  "South Korean Won",
+	"KWD":  "Kuwaiti Dinar",
+	"LKR":  "Sri Lankan Rupee",
+	"MMK":  "Myanmar Kyat",
+	"MXN":  "Mexican Peso",
+	"MYR":  "Malaysian Ringgit",
+	"NGN":  "Nigerian Naira",
+	"NOK":  "Norwegian Krone",
+	"NZD":  "New Zealand Dollar",
+	"PHP":  "Philippine Peso",
+	"PKR":  "Pakistani Rupee",
+	"PLN":  "Polish Złoty",
+	"RUB":  "Russian Ruble",
+	"SAR":  "Saudi Arabian Riyal",
+	"SEK":  "Swedish Krona",
+	"SGD":  "Singapore Dollar",
+	"THB":  "Thai Baht",
+	"TRY":  "Turkish Lira",
+	"TWD":  "New Taiwan Dollar",
+	"UAH":  "Ukrainian Hryvnia",
+	"VEF":  "Venezuelan Bolívar Fuerte",
+	"VND":  "Vietnamese Dong",
+	"ZAR":  "South African Rand",
+	"XDR":  "Special Drawing Rights",
+	"XAG":  "Silver (one troy ounce)",
+	"XAU":  "Gold (one troy ounce)"}
 
 // CGCoin defines a coin and its features.
 type CGCoin struct {
@@ -143,24 +308,70 @@ type CGCoinSingleton struct {
 	Symbol             string           `json:"symbol"`
 	Name               string           `json:"name"`
 	BlockTimeInMinutes float64          `json:"block_time_in_minutes"`
+	MarketCapRank      int              `json:"market_cap_rank"`
 	LastUpdated        string           `json:"last_updated"`
 	Tickers            []CGTicker       `json:"tickers"`
 	MarketData         CGCoinMarketData `json:"market_data"`
 }
 
 // CGCoinMarketData encapsulates price change data over time.
+// Maps are keyed by lower case target currency.
 type CGCoinMarketData struct {
-	PriceChange24h   float64 `json:"price_change_24h"`
-	PriceChange24hPc float64 `json:"price_change_percentage_24h"`
+	CurrentPrice          map[string]float64 `json:"current_price"`
+	MarketCap             map[string]float64 `json:"market_cap"`
+	FullyDilutedValuation map[string]float64 `json:"fully_diluted_valuation"`
+	TotalVolume           map[string]float64 `json:"total_volume"`
+	High24h               map[string]float64 `json:"high_24h"`
+	Low24h                map[string]float64 `json:"low_24h"`
+	Ath                   map[string]float64 `json:"ath"`
+	AthChangePc           map[string]float64 `json:"ath_change_percentage"`
+	PriceChange24h        float64            `json:"price_change_24h"`
+	PriceChange24hPc      float64            `json:"price_change_percentage_24h"`
+	PriceChange24hCurPc   map[string]float64 `json:"price_change_percentage_24h_in_currency"`
+	PriceChange1hPc       map[string]float64 `json:"price_change_percentage_1h_in_currency"`
+	PriceChange7dPc       map[string]float64 `json:"price_change_percentage_7d_in_currency"`
+	PriceChange30dPc      map[string]float64 `json:"price_change_percentage_30d_in_currency"`
+	CirculatingSupply     float64            `json:"circulating_supply"`
+	TotalSupply           float64            `json:"total_supply"`
+	MaxSupply             float64            `json:"max_supply"`
 	// others exist in the JSON
 }
 
 // CGTicker defines tickers which exist for each coin.
 type CGTicker struct {
-	Base       string  `json:"base"`
-	Target     string  `json:"target"`
-	Last       float64 `json:"last"`
-	Volume     float64 `json:"volume"`
-	TrustScore string  `json:"trust_score"`
-	Timestamp  string  `json:"timestamp"`
+	Base           string   `json:"base"`
+	Target         string   `json:"target"`
+	Market         CGMarket `json:"market"`
+	Last           float64  `json:"last"`
+	Volume         float64  `json:"volume"`
+	BidAskSpreadPc float64  `json:"bid_ask_spread_percentage"`
+	TrustScore     string   `json:"trust_score"`
+	Timestamp      string   `json:"timestamp"`
 }
+
+// CGMarket defines the exchange on which a ticker trades.
+type CGMarket struct {
+	Name       string `json:"name"`
+	Identifier string `json:"identifier"`
+}
+
+// CGCoinTickers defines the exchange tickers for a coin, as returned by
+// CGCoinURL + id + CGTickersPath.
+type CGCoinTickers struct {
+	Name    string     `json:"name"`
+	Tickers []CGTicker `json:"tickers"`
+}
+
+// CGTickersPath is appended to a coin URL to get all of its exchange tickers.
+// Query parameters: page.
+const CGTickersPath string = "/tickers"
+
+// CGTickersPerPage is the most tickers CGTickersPath returns per page.
+const CGTickersPerPage int = 100
+
+// TrustScoreRanks is a mapping of ticker trust scores to an order of trust.
+// Tickers without a trust score rank below all of these.
+var TrustScoreRanks = map[string]int{
+	"red":    1,
+	"yellow": 2,
+	"green":  3}
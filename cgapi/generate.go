@@ -0,0 +1,279 @@
+//go:build ignore
+
+// generate.go
+// Generates the embedded coin and currency tables, cgCoinURLs.go,
+// cgCoinNames.go and cgTables.go, from the Coin Gecko API or from a
+// snapshot saved by an earlier run, for offline builds.
+//
+//	go generate ./cgapi
+//	go run generate.go -save=snapshot.json
+//	go run generate.go -snapshot=snapshot.json
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	userAgent     = "ccpc, https://github.com/oishiiburger/ccpc"
+	coinListURL   = "https://api.coingecko.com/api/v3/coins/list"
+	currenciesURL = "https://api.coingecko.com/api/v3/simple/supported_vs_currencies"
+	marketsURL    = "https://api.coingecko.com/api/v3/coins/markets?vs_currency=usd&order=market_cap_desc&per_page=250&page="
+	// rankPages is how many pages of 250 coins by market cap are ranked.
+	rankPages = 4
+)
+
+// snapshot is everything the tables are generated from.
+type snapshot struct {
+	Fetched    time.Time      `json:"fetched"`
+	Coins      []coin         `json:"coins"`
+	Currencies []string       `json:"currencies"`
+	Ranks      map[string]int `json:"ranks"`
+}
+
+// coin is an entry of the coin list.
+type coin struct {
+	ID     string `json:"id"`
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+}
+
+// market is the part of the market data used for ranking.
+type market struct {
+	ID            string `json:"id"`
+	MarketCapRank int    `json:"market_cap_rank"`
+}
+
+func main() {
+	snapPath := flag.String("snapshot", "", "Generates from a saved snapshot instead of the API.")
+	savePath := flag.String("save", "", "Saves the fetched snapshot to this file.")
+	outDir := flag.String("out", ".", "Writes the generated files to this directory.")
+	flag.Parse()
+
+	var snap snapshot
+	var err error
+	if *snapPath != "" {
+		snap, err = readSnapshot(*snapPath)
+	} else {
+		snap, err = fetchSnapshot()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *savePath != "" {
+		data, err := json.MarshalIndent(snap, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*savePath, data, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	urls := dedupe(snap.Coins, snap.Ranks)
+	version := snap.Fetched.UTC().Format("2006-01-02")
+	if err := writeSource(filepath.Join(*outDir, "cgCoinURLs.go"), coinURLsSource(urls)); err != nil {
+		log.Fatal(err)
+	}
+	if err := writeSource(filepath.Join(*outDir, "cgCoinNames.go"), coinNamesSource(snap.Coins, urls)); err != nil {
+		log.Fatal(err)
+	}
+	if err := writeSource(filepath.Join(*outDir, "cgTables.go"), tablesSource(snap.Currencies, version)); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("generated %d coins and %d currencies, version %s", len(urls), len(snap.Currencies), version)
+}
+
+// Reads a snapshot saved with -save.
+func readSnapshot(path string) (snapshot, error) {
+	var snap snapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snap, err
+	}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snap, fmt.Errorf("%s: %v", path, err)
+	}
+	return snap, nil
+}
+
+// Fetches the coin list, the supported currencies and the market cap ranks
+// of the top coins.
+func fetchSnapshot() (snapshot, error) {
+	snap := snapshot{Fetched: time.Now().UTC(), Ranks: make(map[string]int)}
+	if err := getJSON(coinListURL, &snap.Coins); err != nil {
+		return snap, err
+	}
+	if err := getJSON(currenciesURL, &snap.Currencies); err != nil {
+		return snap, err
+	}
+	for page := 1; page <= rankPages; page++ {
+		var markets []market
+		if err := getJSON(marketsURL+strconv.Itoa(page), &markets); err != nil {
+			return snap, err
+		}
+		for _, m := range markets {
+			if m.MarketCapRank > 0 {
+				snap.Ranks[m.ID] = m.MarketCapRank
+			}
+		}
+	}
+	return snap, nil
+}
+
+// Decodes the JSON response of a GET request into v.
+func getJSON(URL string, v interface{}) error {
+	req, err := http.NewRequest("GET", URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", URL, res.Status)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Maps each lower case symbol to a single coin ID. When coins share a
+// symbol, ranked coins beat unranked ones and better ranks beat worse;
+// remaining ties go to the shortest ID, then the first in sort order.
+func dedupe(coins []coin, ranks map[string]int) map[string]string {
+	bySymbol := make(map[string][]string)
+	for _, c := range coins {
+		symb := strings.ToLower(strings.TrimSpace(c.Symbol))
+		if symb == "" || c.ID == "" {
+			continue
+		}
+		bySymbol[symb] = append(bySymbol[symb], c.ID)
+	}
+	urls := make(map[string]string)
+	for symb, ids := range bySymbol {
+		sort.Slice(ids, func(i, j int) bool {
+			ri, rj := ranks[ids[i]], ranks[ids[j]]
+			if (ri > 0) != (rj > 0) {
+				return ri > 0
+			}
+			if ri != rj {
+				return ri < rj
+			}
+			if len(ids[i]) != len(ids[j]) {
+				return len(ids[i]) < len(ids[j])
+			}
+			return ids[i] < ids[j]
+		})
+		urls[symb] = ids[0]
+	}
+	return urls
+}
+
+// Returns the source of cgCoinURLs.go, with entries in coin ID order.
+func coinURLsSource(urls map[string]string) []byte {
+	var symbols []string
+	for symb := range urls {
+		symbols = append(symbols, symb)
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if urls[symbols[i]] != urls[symbols[j]] {
+			return urls[symbols[i]] < urls[symbols[j]]
+		}
+		return symbols[i] < symbols[j]
+	})
+	var b bytes.Buffer
+	b.WriteString("// Code generated by generate.go; DO NOT EDIT.\n\n")
+	b.WriteString("// cgCoinURLs.go\n\npackage cgapi\n\n")
+	b.WriteString("// CGCoinURL is the API URL for the upmost coin array.\n")
+	b.WriteString("const (\n\tCGCoinURL string = \"https://api.coingecko.com/api/v3/coins/\"\n)\n\n")
+	b.WriteString("// CGCoinURLs is a hash table which contains coin symbol keys\n")
+	b.WriteString("// and the corresponding rightmost subdirectory of the Coin Gecko JSON URL values.\n")
+	b.WriteString("var CGCoinURLs = map[string]string{\n")
+	for _, symb := range symbols {
+		fmt.Fprintf(&b, "\t%q: %q,\n", symb, urls[symb])
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// Returns the source of cgCoinNames.go, with the names of the coins which
+// have a symbol in the tables, in coin ID order.
+func coinNamesSource(coins []coin, urls map[string]string) []byte {
+	listed := make(map[string]bool)
+	for _, id := range urls {
+		listed[id] = true
+	}
+	names := make(map[string]string)
+	var ids []string
+	for _, c := range coins {
+		name := strings.TrimSpace(c.Name)
+		if !listed[c.ID] || name == "" || names[c.ID] != "" {
+			continue
+		}
+		names[c.ID] = name
+		ids = append(ids, c.ID)
+	}
+	sort.Strings(ids)
+	var b bytes.Buffer
+	b.WriteString("// Code generated by generate.go; DO NOT EDIT.\n\n")
+	b.WriteString("// cgCoinNames.go\n\npackage cgapi\n\n")
+	b.WriteString("// CGCoinNames is a hash table which contains coin ID keys and the\n")
+	b.WriteString("// corresponding coin name values, where the name is known.\n")
+	b.WriteString("var CGCoinNames = map[string]string{\n")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "\t%q: %q,\n", id, names[id])
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// Returns the source of cgTables.go, with the version stamp and the
+// supported currencies.
+func tablesSource(currencies []string, version string) []byte {
+	var codes []string
+	for _, c := range currencies {
+		codes = append(codes, strings.ToLower(c))
+	}
+	sort.Strings(codes)
+	var b bytes.Buffer
+	b.WriteString("// Code generated by generate.go; DO NOT EDIT.\n\n")
+	b.WriteString("// cgTables.go\n\npackage cgapi\n\n")
+	b.WriteString("// TablesVersion is the date the embedded coin and currency tables were fetched.\n")
+	fmt.Fprintf(&b, "const TablesVersion string = %q\n\n", version)
+	b.WriteString("// SupportedCurrencies are the lower case target currencies supported by\n")
+	b.WriteString("// the API when the tables were fetched.\n")
+	b.WriteString("var SupportedCurrencies = []string{\n")
+	for _, c := range codes {
+		fmt.Fprintf(&b, "\t%q,\n", c)
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// Formats and writes generated source.
+func writeSource(path string, src []byte) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return os.WriteFile(path, formatted, 0o644)
+}
@@ -0,0 +1,153 @@
+// coins.go
+// The names and market cap ranks of the coins in the embedded coin table,
+// used to search, suggest and resolve coins by name. They are fetched from
+// the API's coin list and cached for a day. If they cannot be loaded, the
+// embedded names in cgapi are used instead, without ranks.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"ccpc/cgapi"
+)
+
+const (
+	coinListCacheFile = "coins_list.json"
+	coinListCacheTTL  = 24 * time.Hour
+)
+
+// coinList is the cached part of the coin list: the names of the coins in
+// the embedded table, and the market cap ranks of the largest coins.
+type coinList struct {
+	Names map[string]string `json:"names"`
+	Ranks map[string]int    `json:"ranks"`
+}
+
+// knownCoins holds the coin list, loaded on first use, and the coin IDs by
+// normalized name and ID built from it.
+var knownCoins struct {
+	once   sync.Once
+	list   coinList
+	byName map[string]string
+}
+
+// coinSymbols holds the first symbol of each coin ID in the embedded table,
+// built on first use.
+var coinSymbols struct {
+	once sync.Once
+	byID map[string]string
+}
+
+// Returns the name of a coin, or an empty string if it is not known.
+func coinName(id string) string {
+	loadCoins()
+	return knownCoins.list.Names[id]
+}
+
+// Returns the market cap rank of a coin, or zero if it is not ranked.
+func coinRank(id string) int {
+	loadCoins()
+	return knownCoins.list.Ranks[id]
+}
+
+// Returns a map of normalized coin names and IDs to coin IDs. A name wins
+// over another coin's ID, and otherwise the first ID in sort order wins.
+func coinIDsByName() map[string]string {
+	loadCoins()
+	return knownCoins.byName
+}
+
+// Returns a map of coin IDs to the first of their symbols in CGCoinURLs.
+func coinSymbolsByID() map[string]string {
+	coinSymbols.once.Do(func() {
+		coinSymbols.byID = make(map[string]string)
+		for _, symb := range mapToSortedStrings(cgapi.CGCoinURLs) {
+			id := cgapi.CGCoinURLs[symb]
+			if _, ok := coinSymbols.byID[id]; !ok {
+				coinSymbols.byID[id] = symb
+			}
+		}
+	})
+	return coinSymbols.byID
+}
+
+// Loads the coin list on first use and builds the name lookup from it.
+func loadCoins() {
+	knownCoins.once.Do(func() {
+		list, err := loadCoinList(context.Background())
+		if err != nil {
+			verboseMessage("using the built-in coin names: %v", err)
+			list = coinList{Names: cgapi.CGCoinNames}
+		}
+		knownCoins.list = list
+		knownCoins.byName = make(map[string]string)
+		sorted := mapToSortedStrings(coinSymbolsByID())
+		for _, id := range sorted {
+			if name := normalName(list.Names[id]); name != "" {
+				if _, ok := knownCoins.byName[name]; !ok {
+					knownCoins.byName[name] = id
+				}
+			}
+		}
+		for _, id := range sorted {
+			if _, ok := knownCoins.byName[normalName(id)]; !ok {
+				knownCoins.byName[normalName(id)] = id
+			}
+		}
+	})
+}
+
+// Loads the coin list from the cache, fetching it if the cache is missing
+// or expired. An expired cache is used if the fetch fails.
+func loadCoinList(ctx context.Context) (coinList, error) {
+	var list coinList
+	fresh, cacheErr := readCache(coinListCacheFile, coinListCacheTTL, &list)
+	if cacheErr != nil || !fresh {
+		fetched, err := fetchCoinList(ctx)
+		if err == nil {
+			list = fetched
+			writeCache(coinListCacheFile, list)
+		} else if cacheErr != nil {
+			return list, err
+		}
+	}
+	return list, nil
+}
+
+// Fetches the names of the coins in the embedded table from the API, and
+// the ranks of the first page of coins by market cap.
+func fetchCoinList(ctx context.Context) (coinList, error) {
+	list := coinList{Names: make(map[string]string), Ranks: make(map[string]int)}
+	res, err := httpRequest(ctx, cgapi.CGCoinListURL, userAgent)
+	if err != nil {
+		return list, err
+	}
+	var coins cgapi.CGCoinList
+	if err := json.Unmarshal(res, &coins); err != nil {
+		return list, err
+	}
+	if len(coins) == 0 {
+		return list, errors.New("no coins were returned")
+	}
+	symbols := coinSymbolsByID()
+	for _, c := range coins {
+		if _, ok := symbols[c.ID]; ok && c.Name != "" {
+			list.Names[c.ID] = c.Name
+		}
+	}
+	markets, err := fetchMarkets(ctx, cgapi.CGMarketsPerPage, nil, "usd")
+	if err != nil {
+		return list, err
+	}
+	for _, m := range markets {
+		if m.MarketCapRank > 0 {
+			list.Ranks[m.ID] = m.MarketCapRank
+		}
+	}
+	return list, nil
+}
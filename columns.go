@@ -0,0 +1,285 @@
+// columns.go
+// The columns of a coin listing. Each column renders one cell of a ticker;
+// the listing's flags or --columns choose which columns are shown and how.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ccpc/cgapi"
+)
+
+// columnSpec defines a column of a listing and how it is laid out.
+// Fixed widths were given with --columns and are kept by adaptive layout.
+// Decimal aligned columns place the decimal point of each cell at column
+// decimal, which is half the width until the layout is fitted.
+// Columns with a target show it instead of the listing's first target.
+type columnSpec struct {
+	name    string
+	width   int
+	align   string
+	fixed   bool
+	decimal int
+	target  string
+}
+
+// listingColumn defines how a column is rendered and its default width.
+type listingColumn struct {
+	cell  func(coin cgapi.CGCoinSingleton, list listing) (string, paint)
+	width func(list listing) int
+}
+
+// listingColumns maps column names to their definitions.
+var listingColumns = map[string]listingColumn{
+	"symbol":    {symbolCell, func(l listing) int { return l.symbolWidth }},
+	"name":      {nameCell, func(l listing) int { return l.nameWidth }},
+	"price":     {priceCell, func(l listing) int { return l.priceWidth }},
+	"change24h": {change24hCell, func(l listing) int { return l.changeWidth }},
+	"updated":   {updatedCell, func(l listing) int { return l.lastUpdatedWidth }},
+	"vol":       {volumeCell, func(l listing) int { return l.volumeWidth }},
+	"blocktime": {blockTimeCell, func(l listing) int { return l.blockTIMWidth }},
+	"rank":      {rankCell, func(l listing) int { return l.rankWidth }},
+	"mcap":      {marketCapCell, func(l listing) int { return l.marketCapWidth }},
+	"fdv":       {fdvCell, func(l listing) int { return l.fdvWidth }},
+	"highlow":   {highLowCell, func(l listing) int { return l.highLowWidth }},
+	"ath":       {athCell, func(l listing) int { return l.athWidth }},
+	"supply":    {supplyCell, func(l listing) int { return l.supplyWidth }},
+	"change1h":  {change1hCell, func(l listing) int { return l.changeWidth }},
+	"change7d":  {change7dCell, func(l listing) int { return l.changeWidth }},
+	"change30d": {change30dCell, func(l listing) int { return l.changeWidth }},
+	"holding":   {holdingCell, func(l listing) int { return l.holdingWidth }},
+	"alert":     {alertCell, func(l listing) int { return l.alertWidth }},
+}
+
+// columnOrder is the order of the columns chosen by the listing flags.
+var columnOrder = []string{"symbol", "name", "price", "alert", "holding", "updated", "vol", "blocktime", "rank", "mcap", "fdv",
+	"highlow", "ath", "supply", "change1h", "change7d", "change30d"}
+
+// Returns the columns of a listing: those given by --columns, or else those
+// chosen by the listing flags, in columnOrder.
+func (l listing) layout() []columnSpec {
+	if l.columns != nil {
+		return l.perTarget(l.columns)
+	}
+	chosen := map[string]bool{
+		"symbol":    l.symbol,
+		"name":      l.name,
+		"price":     true,
+		"alert":     l.alerts,
+		"holding":   l.holdings,
+		"updated":   l.lastUpdated,
+		"vol":       l.volume,
+		"blocktime": l.blockTIM,
+		"rank":      l.rank,
+		"mcap":      l.marketCap,
+		"fdv":       l.fdv,
+		"highlow":   l.highLow,
+		"ath":       l.ath,
+		"supply":    l.supply,
+		"change1h":  l.change1h,
+		"change7d":  l.change7d,
+		"change30d": l.change30d,
+	}
+	var specs []columnSpec
+	for _, name := range columnOrder {
+		if chosen[name] {
+			specs = append(specs, columnSpec{name: name, width: listingColumns[name].width(l), align: "center"})
+		}
+	}
+	return l.perTarget(specs)
+}
+
+// Repeats the price and change24h columns for each target of a listing
+// with more than one. The first of each keeps the listing's target, so
+// that coins with their own target show it there. Columns which were
+// already repeated are returned as they are.
+func (l listing) perTarget(specs []columnSpec) []columnSpec {
+	if len(l.targets) < 2 {
+		return specs
+	}
+	for _, spec := range specs {
+		if spec.target != "" {
+			return specs
+		}
+	}
+	var out []columnSpec
+	for _, spec := range specs {
+		out = append(out, spec)
+		if spec.name != "price" && spec.name != "change24h" {
+			continue
+		}
+		for _, tgt := range l.targets[1:] {
+			spec.target = tgt
+			out = append(out, spec)
+		}
+	}
+	return out
+}
+
+// Returns the listing a coin is rendered with, in its own target if it has one.
+func (l listing) forCoin(coin cgapi.CGCoinSingleton) listing {
+	if tgt := l.coinEntries[coin.ID].target; tgt != "" {
+		l.target = tgt
+	}
+	return l
+}
+
+// Returns the listing a column's cells are rendered with.
+func (spec columnSpec) listing(l listing) listing {
+	if spec.target != "" {
+		l.target = spec.target
+	}
+	return l
+}
+
+// Reports whether a column was chosen with --columns. The listing flags
+// never choose change24h, which is the only column this is needed for.
+func (l listing) hasColumn(name string) bool {
+	for _, spec := range l.columns {
+		if spec.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Parses a --columns option, a comma separated list of name[:width][:align],
+// e.g. "symbol,name:30:left,price:28:decimal". Alignments are left, right,
+// center and decimal.
+func parseColumns(opt string, list listing) ([]columnSpec, error) {
+	var specs []columnSpec
+	for _, field := range strings.Split(opt, ",") {
+		parts := strings.Split(strings.TrimSpace(field), ":")
+		name := strings.ToLower(parts[0])
+		col, ok := listingColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column '%s'; use %s", name, strings.Join(mapKeys(listingColumns), ", "))
+		}
+		spec := columnSpec{name: name, width: col.width(list), align: "center"}
+		for _, p := range parts[1:] {
+			switch p = strings.ToLower(p); p {
+			case "left", "right", "center", "decimal":
+				spec.align = p
+			default:
+				w, err := strconv.Atoi(p)
+				if err != nil || w < 1 {
+					return nil, fmt.Errorf("column '%s' has an invalid width or alignment '%s'", name, p)
+				}
+				spec.width = w
+				spec.fixed = true
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// Returns the sorted column names.
+func mapKeys(mp map[string]listingColumn) []string {
+	var keys []string
+	for k := range mp {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func symbolCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	return coin.Symbol, paintAccent
+}
+
+func nameCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	return coin.Name, paintLabel
+}
+
+// Show the price and, without a change24h column, the 24h change.
+// A '*' marks a stale ticker and a '~' a market data price.
+func priceCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	last, ok := coinPrice(coin, list)
+	if !ok {
+		return "no price", paintWarn
+	}
+	price := list.numbers.price(last, list.target)
+	if ticker, ok := selectTicker(coin.Tickers, list); ok && tickerIsStale(ticker, list.staleAfter) {
+		price += "*"
+	} else if marketPriceFallback(coin, list) {
+		price += "~"
+	}
+	pc := coinChange24h(coin, list)
+	if !list.hasColumn("change24h") && pc != 0 {
+		per := list.numbers.percent(pc, true)
+		if list.compact {
+			price += " " + per
+		} else {
+			price += " (" + per + "/24h)"
+		}
+	}
+	return price, movePaint(pc)
+}
+
+func change24hCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	pc := coinChange24h(coin, list)
+	return "24h:" + list.numbers.percent(pc, true), movePaint(pc)
+}
+
+// Show when the API last updated the coin.
+func updatedCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	tm, err := time.Parse(time.RFC3339Nano, coin.LastUpdated)
+	if err != nil {
+		return "UPD:n/a", paintInfo
+	}
+	if list.compact {
+		return tm.Local().Format("15:04"), paintInfo
+	}
+	return "UPD:" + tm.Format(time.RFC822), paintInfo
+}
+
+// Show the 24h volume of the selected ticker.
+func volumeCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	ticker, ok := selectTicker(coin.Tickers, list)
+	if !ok {
+		return "no volume", paintInfo
+	}
+	return "VOL:" + list.numbers.compact(ticker.Volume), paintInfo
+}
+
+func blockTimeCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	return "BT:" + list.numbers.number(coin.BlockTimeInMinutes, 1), paintInfo
+}
+
+// Show the value of the quantity from the symbols file.
+func holdingCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	qty := list.coinEntries[coin.ID].quantity
+	if qty == 0 {
+		return "-", paintInfo
+	}
+	last, ok := coinPrice(coin, list)
+	if !ok {
+		return "HLD:n/a", paintInfo
+	}
+	return "HLD:" + list.numbers.price(last*qty, list.target), paintInfo
+}
+
+// Show which alert threshold from the symbols file the price has crossed.
+func alertCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	e := list.coinEntries[coin.ID]
+	if e.alertAbove == 0 && e.alertBelow == 0 {
+		return "-", paintInfo
+	}
+	last, ok := coinPrice(coin, list)
+	if !ok {
+		return "alert n/a", paintInfo
+	}
+	if e.alertAbove != 0 && last >= e.alertAbove {
+		return "ABOVE " + list.numbers.price(e.alertAbove, list.target), paintWarn
+	}
+	if e.alertBelow != 0 && last <= e.alertBelow {
+		return "BELOW " + list.numbers.price(e.alertBelow, list.target), paintWarn
+	}
+	return "no alert", paintInfo
+}
@@ -0,0 +1,41 @@
+// config.go
+// User configuration is kept in a ccpc directory under the user's config
+// directory, e.g. ~/.config/ccpc on Linux.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Returns the ccpc config directory. It is not created.
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ccpc"), nil
+}
+
+// Decodes a JSON file in the config directory into v. A missing file is
+// not an error and leaves v unchanged.
+func readConfigFile(name string, v interface{}) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.New(name + ": " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// currencies.go
+// The target currencies supported by the Coin Gecko API. The list is fetched
+// from the API and cached for a day, and the embedded tables in cgapi give
+// the symbols and names shown for them. If the list cannot be loaded, the
+// embedded list of supported currencies is used instead.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"ccpc/cgapi"
+)
+
+const (
+	currencyCacheFile = "supported_vs_currencies.json"
+	currencyCacheTTL  = 24 * time.Hour
+)
+
+// supportedCurrencies holds the upper case abbreviations of the supported
+// currencies, loaded on first use.
+var supportedCurrencies struct {
+	once  sync.Once
+	codes map[string]bool
+}
+
+// Reports whether the API supports a currency as a target.
+func knownCurrency(code string) bool {
+	return currencyCodes()[strings.ToUpper(code)]
+}
+
+// Returns the supported currencies, loading them on first use.
+func currencyCodes() map[string]bool {
+	supportedCurrencies.once.Do(func() {
+		codes, err := loadCurrencyCodes(context.Background())
+		if err != nil {
+			codes = make(map[string]bool)
+			for _, code := range cgapi.SupportedCurrencies {
+				codes[strings.ToUpper(code)] = true
+			}
+		}
+		supportedCurrencies.codes = codes
+	})
+	return supportedCurrencies.codes
+}
+
+// Loads the supported currencies from the cache, fetching them if the cache
+// is missing or expired. An expired cache is used if the fetch fails.
+func loadCurrencyCodes(ctx context.Context) (map[string]bool, error) {
+	var list cgapi.CGSupportedCurrencies
+	fresh, cacheErr := readCache(currencyCacheFile, currencyCacheTTL, &list)
+	if cacheErr != nil || !fresh {
+		fetched, err := fetchCurrencyCodes(ctx)
+		if err == nil {
+			list = fetched
+			writeCache(currencyCacheFile, list)
+		} else if cacheErr != nil {
+			return nil, err
+		}
+	}
+	codes := make(map[string]bool)
+	for _, code := range list {
+		codes[strings.ToUpper(code)] = true
+	}
+	return codes, nil
+}
+
+// Fetches the supported currencies from the API.
+func fetchCurrencyCodes(ctx context.Context) (cgapi.CGSupportedCurrencies, error) {
+	res, err := httpRequest(ctx, cgapi.CGSupportedCurrenciesURL, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	var list cgapi.CGSupportedCurrencies
+	if err := json.Unmarshal(res, &list); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no currencies were returned")
+	}
+	return list, nil
+}
+
+// Lists the supported currencies with their symbols and names. Currencies
+// missing from the embedded tables are shown by abbreviation only.
+func listCurrencies() {
+	symbols := make(map[string]string)
+	names := make(map[string]string)
+	for code := range currencyCodes() {
+		symbols[code] = currencySymbol(code)
+		names[code] = cgapi.MonetaryNames[code]
+	}
+	listTableKeys(symbols, "currencies", names)
+}
+
+// Returns the symbol of a currency, or its abbreviation if it has none.
+func currencySymbol(code string) string {
+	if sym := cgapi.MonetarySymbols[code]; sym != "" {
+		return sym
+	}
+	return code
+}
@@ -0,0 +1,136 @@
+// filter.go
+// Sorting and filtering of coin listings, applied after fetching.
+
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"ccpc/cgapi"
+)
+
+// listingFilter defines which coin listings are shown and in which order.
+// Nil thresholds are not applied.
+type listingFilter struct {
+	sortBy    string
+	desc      bool
+	minChange *float64
+	maxPrice  *float64
+	minVolume *float64
+	top       int
+}
+
+// sortFields is the set of fields which listings can be sorted by.
+var sortFields = map[string]bool{
+	"price":     true,
+	"change":    true,
+	"volume":    true,
+	"name":      true,
+	"marketcap": true,
+}
+
+// Parses a sort option of the form field[:asc|:desc] into the filter.
+// Numeric fields sort descending by default and names ascending.
+func (f *listingFilter) parseSort(opt string) bool {
+	field, order := opt, ""
+	if i := strings.Index(opt, ":"); i >= 0 {
+		field, order = opt[:i], opt[i+1:]
+	}
+	field = strings.ToLower(field)
+	if !sortFields[field] {
+		return false
+	}
+	f.sortBy = field
+	switch strings.ToLower(order) {
+	case "":
+		f.desc = field != "name"
+	case "asc":
+		f.desc = false
+	case "desc":
+		f.desc = true
+	default:
+		return false
+	}
+	return true
+}
+
+// Reports whether the filter needs every listing before any can be shown.
+func (f listingFilter) active() bool {
+	return f.sortBy != "" || f.minChange != nil || f.maxPrice != nil || f.minVolume != nil || f.top > 0
+}
+
+// Returns the coins which pass the filter, sorted and cut to the top N.
+func filterCoins(coins []cgapi.CGCoinSingleton, list listing, f listingFilter) []cgapi.CGCoinSingleton {
+	var out []cgapi.CGCoinSingleton
+	for _, c := range coins {
+		if !passesThreshold(c, list, "change", f.minChange, false) ||
+			!passesThreshold(c, list, "price", f.maxPrice, true) ||
+			!passesThreshold(c, list, "volume", f.minVolume, false) {
+			continue
+		}
+		out = append(out, c)
+	}
+	if f.sortBy == "name" {
+		sort.SliceStable(out, func(i, j int) bool {
+			a, b := strings.ToLower(out[i].Name), strings.ToLower(out[j].Name)
+			if f.desc {
+				return a > b
+			}
+			return a < b
+		})
+	} else if f.sortBy != "" {
+		sort.SliceStable(out, func(i, j int) bool {
+			a, aok := coinValue(out[i], list, f.sortBy)
+			b, bok := coinValue(out[j], list, f.sortBy)
+			if !aok || !bok {
+				// Coins without the value are sorted last either way.
+				return aok
+			}
+			if f.desc {
+				return a > b
+			}
+			return a < b
+		})
+	}
+	if f.top > 0 && len(out) > f.top {
+		out = out[:f.top]
+	}
+	return out
+}
+
+// Reports whether a coin is within a minimum or maximum threshold.
+// Coins without the value never pass a threshold which is set.
+func passesThreshold(c cgapi.CGCoinSingleton, list listing, field string, limit *float64, max bool) bool {
+	if limit == nil {
+		return true
+	}
+	val, ok := coinValue(c, list, field)
+	if !ok {
+		return false
+	}
+	if max {
+		return val <= *limit
+	}
+	return val >= *limit
+}
+
+// Returns a numeric field of a coin in the listing's target currency.
+// Price is the price shown in the listing; volume and market cap are
+// totals across all markets.
+func coinValue(c cgapi.CGCoinSingleton, list listing, field string) (float64, bool) {
+	tgt := strings.ToLower(list.target)
+	switch field {
+	case "price":
+		return coinPrice(c, list)
+	case "change":
+		return coinChange24h(c, list), len(c.Symbol) > 0
+	case "volume":
+		v, ok := c.MarketData.TotalVolume[tgt]
+		return v, ok
+	case "marketcap":
+		v, ok := c.MarketData.MarketCap[tgt]
+		return v, ok
+	}
+	return 0, false
+}
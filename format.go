@@ -0,0 +1,193 @@
+// format.go
+// Locale-aware formatting of prices, amounts and percentages.
+
+package main
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"ccpc/cgapi"
+)
+
+const (
+	// defaultDecimals is the number of decimals of currencies without a
+	// rule in cgapi.MonetaryDecimals.
+	defaultDecimals = 2
+	// priceSigDigits is the least number of significant digits shown for
+	// prices which are small in their currency.
+	priceSigDigits = 3
+	// maxDecimals caps the decimals shown for very small prices.
+	maxDecimals = 12
+)
+
+// numberFormat defines how numbers are written in a locale.
+type numberFormat struct {
+	group       string
+	decimal     string
+	symbolAfter bool
+	symbolSpace bool
+}
+
+// numberFormats is a mapping of locales, as language or language_REGION,
+// to their number formats.
+var numberFormats = map[string]numberFormat{
+	"en":    {",", ".", false, false},
+	"ja":    {",", ".", false, false},
+	"zh":    {",", ".", false, false},
+	"ko":    {",", ".", false, false},
+	"hi":    {",", ".", false, false},
+	"de":    {".", ",", true, true},
+	"de_CH": {"'", ".", false, true},
+	"nl":    {".", ",", false, true},
+	"es":    {".", ",", true, true},
+	"it":    {".", ",", true, true},
+	"pt":    {".", ",", true, true},
+	"pt_BR": {".", ",", false, true},
+	"id":    {".", ",", false, false},
+	"tr":    {".", ",", false, false},
+	"da":    {".", ",", true, true},
+	"fr":    {" ", ",", true, true},
+	"fr_CH": {" ", ".", true, true},
+	"ru":    {" ", ",", true, true},
+	"uk":    {" ", ",", true, true},
+	"pl":    {" ", ",", true, true},
+	"cs":    {" ", ",", true, true},
+	"sv":    {" ", ",", true, true},
+	"nb":    {" ", ",", true, true},
+	"fi":    {" ", ",", true, true},
+}
+
+// Returns the number format of a locale such as "de_DE.UTF-8", falling back
+// to its language and then to English. An empty locale is taken from the
+// LC_ALL, LC_NUMERIC and LANG environment variables, in that order.
+func localeNumberFormat(locale string) (numberFormat, bool) {
+	if locale == "" {
+		for _, env := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+			if locale = os.Getenv(env); locale != "" {
+				break
+			}
+		}
+	}
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.Replace(locale, "-", "_", 1)
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return numberFormats["en"], true
+	}
+	if nf, ok := numberFormats[locale]; ok {
+		return nf, true
+	}
+	lang := strings.ToLower(strings.SplitN(locale, "_", 2)[0])
+	if nf, ok := numberFormats[lang]; ok {
+		return nf, true
+	}
+	return numberFormats["en"], false
+}
+
+// Returns a number with the given decimals and grouped thousands.
+func (nf numberFormat) number(v float64, decimals int) string {
+	str := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, frac := str, ""
+	if i := strings.Index(str, "."); i >= 0 {
+		whole, frac = str[:i], str[i+1:]
+	}
+	buf := new(strings.Builder)
+	if v < 0 && strings.Trim(str, "0.") != "" {
+		buf.WriteString("-")
+	}
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			buf.WriteString(nf.group)
+		}
+		buf.WriteRune(r)
+	}
+	if frac != "" {
+		buf.WriteString(nf.decimal + frac)
+	}
+	return buf.String()
+}
+
+// Returns a price with the currency symbol of the target, using the
+// target's decimals or enough decimals for priceSigDigits significant digits.
+func (nf numberFormat) price(v float64, target string) string {
+	return nf.withSymbol(nf.number(v, priceDecimals(v, target)), target)
+}
+
+// Returns an amount in the target currency in compact notation.
+func (nf numberFormat) amount(v float64, target string) string {
+	return nf.withSymbol(nf.compact(v), target)
+}
+
+// Returns a number with a K, M, B or T suffix, e.g. 1.23M. The suffix is
+// chosen after rounding, so 999999 is 1.00M rather than 1,000.00K.
+func (nf numberFormat) compact(v float64) string {
+	suffixes := []string{"", "K", "M", "B", "T"}
+	i := 0
+	for math.Abs(math.Round(v*100)/100) >= 1000 && i < len(suffixes)-1 {
+		v /= 1000
+		i++
+	}
+	return nf.number(v, 2) + suffixes[i]
+}
+
+// Returns a percentage with two decimals and, if asked, a sign when positive.
+func (nf numberFormat) percent(pc float64, sign bool) string {
+	str := nf.number(pc, 2) + "%"
+	if sign && pc >= 0 {
+		str = "+" + str
+	}
+	return str
+}
+
+// Places the symbol of the target currency before or after a number.
+// Currencies without a known symbol are shown by abbreviation after it,
+// and so are symbols which are codes, such as btc or sats.
+func (nf numberFormat) withSymbol(num, target string) string {
+	sym := cgapi.MonetarySymbols[target]
+	if sym == "" && target != "" {
+		return num + " " + target
+	} else if sym == "" {
+		return num
+	} else if codeSymbol(sym, target) {
+		return num + " " + sym
+	}
+	space := ""
+	if nf.symbolSpace {
+		space = " "
+	}
+	if nf.symbolAfter {
+		return num + space + sym
+	}
+	if strings.HasPrefix(num, "-") {
+		return "-" + sym + space + num[1:]
+	}
+	return sym + space + num
+}
+
+// Reports whether a currency symbol is a code rather than a sign, e.g. btc,
+// CHF or μBTC.
+func codeSymbol(sym, target string) bool {
+	return strings.EqualFold(sym, target) || strings.EqualFold(strings.TrimPrefix(sym, "μ"), "BTC")
+}
+
+// Returns the decimals used for a price in the target currency.
+func priceDecimals(v float64, target string) int {
+	decimals, ok := cgapi.MonetaryDecimals[target]
+	if !ok {
+		decimals = defaultDecimals
+	}
+	if v != 0 && math.Abs(v) < 1 {
+		sig := priceSigDigits - 1 - int(math.Floor(math.Log10(math.Abs(v))))
+		if sig > decimals {
+			decimals = sig
+		}
+	}
+	if decimals > maxDecimals {
+		decimals = maxDecimals
+	}
+	return decimals
+}
@@ -0,0 +1,90 @@
+// get.go
+// The get command prints a single bare value for a coin, for shell prompts
+// and status bars. It prints nothing else to stdout, caches values between
+// runs, falls back to an expired cached value if the API cannot be reached,
+// and exits with a non-zero code if the value cannot be shown.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getFields maps the fields of the get command to their keys in the
+// simple price data, after the target currency.
+var getFields = map[string]string{
+	"price":     "",
+	"change24h": "_24h_change",
+	"volume":    "_24h_vol",
+	"marketcap": "_market_cap",
+}
+
+// Prints a field of a coin in the listing's target currency.
+func runGet(ctx context.Context, args []string, field string, ttl time.Duration, list listing) {
+	if len(args) != 1 {
+		getFailed("the get command takes exactly one coin symbol")
+	}
+	field = strings.ToLower(field)
+	suffix, ok := getFields[field]
+	if !ok {
+		getFailed("unknown field '" + field + "'; use " + strings.Join(mapToSortedStrings(getFields), ", "))
+	}
+	symb, id := lookupCoin(args[0], list)
+	if id == "" {
+		getFailed("unknown coin symbol '" + symb + "'")
+	}
+	tgt := strings.ToLower(list.target)
+	data, err := cachedSimplePrice(ctx, id, tgt, ttl)
+	exitIfInterrupted(ctx)
+	if err != nil {
+		getFailed("could not load " + symb + ": " + err.Error())
+	}
+	v, ok := data[tgt+suffix]
+	if !ok {
+		getFailed("no " + field + " for " + symb + " in " + list.target)
+	}
+	switch field {
+	case "price":
+		fmt.Println(strconv.FormatFloat(v, 'f', priceDecimals(v, list.target), 64))
+	case "change24h":
+		fmt.Println(strconv.FormatFloat(v, 'f', 2, 64))
+	default:
+		fmt.Println(strconv.FormatFloat(v, 'f', 0, 64))
+	}
+}
+
+// Returns the simple price data of a coin in a target, from the cache if
+// it is younger than ttl. Otherwise it is fetched and cached; if the fetch
+// fails, an older cached value is returned rather than none.
+func cachedSimplePrice(ctx context.Context, id, tgt string, ttl time.Duration) (map[string]float64, error) {
+	name := "price-" + id + "-" + tgt + ".json"
+	var data map[string]float64
+	fresh, cacheErr := readCache(name, ttl, &data)
+	if cacheErr == nil && fresh {
+		return data, nil
+	}
+	prices, err := fetchSimplePrices(ctx, []string{id}, []string{tgt})
+	if err == nil {
+		if fetched, ok := prices[id]; ok {
+			writeCache(name, fetched)
+			return fetched, nil
+		}
+		err = fmt.Errorf("no price data was returned")
+	}
+	if cacheErr == nil {
+		verboseMessage("using an expired cached value for %s in %s: %v", id, tgt, err)
+		return data, nil
+	}
+	return nil, err
+}
+
+// Reports a failure of the get command on stderr and exits.
+func getFailed(msg string) {
+	fmt.Fprintln(os.Stderr, "ccpc: "+msg)
+	os.Exit(1)
+}
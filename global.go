@@ -0,0 +1,85 @@
+// global.go
+// The global market overview, shown by the global command or above listings.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ccpc/cgapi"
+)
+
+const (
+	globalMarketCapWidth = 32
+	globalDominanceWidth = 22
+)
+
+// Fetches the global market data.
+func fetchGlobal(ctx context.Context) (cgapi.CGGlobalData, error) {
+	res, err := httpRequest(ctx, cgapi.CGGlobalURL, userAgent)
+	if err != nil {
+		return cgapi.CGGlobalData{}, err
+	}
+	var global cgapi.CGGlobal
+	if err := json.Unmarshal(res, &global); err != nil {
+		return cgapi.CGGlobalData{}, err
+	}
+	return global.Data, nil
+}
+
+// Shows the global market overview.
+func runGlobal(ctx context.Context, list listing) {
+	global, err := fetchGlobal(ctx)
+	if err != nil {
+		requestFailed(ctx, list)
+	}
+	if list.jsonOutput {
+		printJSON(newJSONGlobal(global, list))
+		return
+	}
+	generateGlobalTicker(global, list)
+}
+
+// Shows the global market overview above a listing, if the listing
+// includes it. JSON listings include it instead, so it is returned for
+// them. Failures are reported without exiting.
+func printGlobalHeader(ctx context.Context, list listing) *cgapi.CGGlobalData {
+	if !list.global {
+		return nil
+	}
+	global, err := fetchGlobal(ctx)
+	if err != nil {
+		usrMessage("Could not load global market data.", false, list)
+		return nil
+	}
+	if !list.jsonOutput {
+		generateGlobalTicker(global, list)
+	}
+	return &global
+}
+
+// Generate a ticker for the global market data. The market cap change is
+// always in USD, whatever the target.
+func generateGlobalTicker(global cgapi.CGGlobalData, list listing) {
+	tgt := strings.ToLower(list.target)
+	tPrint("global", true, list, paintAccent, list.symbolWidth)
+	if mcap, ok := global.TotalMarketCap[tgt]; ok {
+		mcapStr := "MCAP:" + list.numbers.amount(mcap, list.target) + " (USD " +
+			list.numbers.percent(global.MarketCapChange24hUSDPc, true) + "/24h)"
+		tPrint(mcapStr, true, list, movePaint(global.MarketCapChange24hUSDPc), globalMarketCapWidth)
+	} else {
+		tPrint("no market cap", true, list, paintWarn, globalMarketCapWidth)
+	}
+	if vol, ok := global.TotalVolume[tgt]; ok {
+		tPrint("VOL:"+list.numbers.amount(vol, list.target), true, list, paintInfo, list.volumeWidth)
+	} else {
+		tPrint("no volume", true, list, paintInfo, list.volumeWidth)
+	}
+	tPrint("BTC:"+list.numbers.percent(global.MarketCapPercentage["btc"], false)+
+		" ETH:"+list.numbers.percent(global.MarketCapPercentage["eth"], false), true, list, paintInfo,
+		globalDominanceWidth)
+	fmt.Println(" ")
+}
@@ -0,0 +1,124 @@
+// jsonoutput.go
+// With --json, listings and the global market overview are printed on
+// stdout as one JSON document instead of a table, with the price and 24h
+// change of each coin in every target.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"ccpc/cgapi"
+)
+
+// jsonListing is a listing printed with --json. Global is included with
+// --global.
+type jsonListing struct {
+	Global *jsonGlobal `json:"global,omitempty"`
+	Coins  []jsonCoin  `json:"coins"`
+}
+
+// jsonGlobal is the global market data in the listing target. The market
+// cap and volume are null when they are not known in the target. The
+// market cap change is of the market cap in USD.
+type jsonGlobal struct {
+	Target                  string   `json:"target"`
+	TotalMarketCap          *float64 `json:"total_market_cap"`
+	TotalVolume             *float64 `json:"total_volume"`
+	MarketCapChange24hUSDPc float64  `json:"market_cap_change_24h_usd"`
+	BTCDominance            float64  `json:"btc_dominance"`
+	ETHDominance            float64  `json:"eth_dominance"`
+}
+
+// jsonCoin is a coin of a listing printed with --json. Prices are in the
+// order of the targets, starting with the coin's own target if it has one.
+type jsonCoin struct {
+	Symbol      string      `json:"symbol"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Prices      []jsonPrice `json:"prices"`
+	LastUpdated string      `json:"last_updated"`
+}
+
+// jsonPrice is the price of a coin in one target. Price is null when the
+// coin has no price in the target. Source is "ticker" for the last price of
+// an exchange ticker, or "market" for the market data price.
+type jsonPrice struct {
+	Target      string   `json:"target"`
+	Price       *float64 `json:"price"`
+	Change24hPc float64  `json:"change_24h"`
+	Source      string   `json:"source,omitempty"`
+	Stale       bool     `json:"stale,omitempty"`
+}
+
+// Prints coins as a JSON listing on stdout, with the global market data
+// if it is not nil.
+func printJSONListing(coins []cgapi.CGCoinSingleton, list listing, global *cgapi.CGGlobalData) {
+	out := jsonListing{Coins: []jsonCoin{}}
+	if global != nil {
+		g := newJSONGlobal(*global, list)
+		out.Global = &g
+	}
+	for _, coin := range coins {
+		if len(coin.Symbol) < 1 {
+			continue
+		}
+		cl := list.forCoin(coin)
+		targets := []string{cl.target}
+		if len(list.targets) > 1 {
+			targets = append(targets, list.targets[1:]...)
+		}
+		jc := jsonCoin{Symbol: coin.Symbol, ID: coin.ID, Name: coin.Name, LastUpdated: coin.LastUpdated}
+		for _, tgt := range targets {
+			cl.target = tgt
+			jc.Prices = append(jc.Prices, jsonCoinPrice(coin, cl))
+		}
+		out.Coins = append(out.Coins, jc)
+	}
+	printJSON(out)
+}
+
+// Returns the global market data in the listing target.
+func newJSONGlobal(global cgapi.CGGlobalData, list listing) jsonGlobal {
+	tgt := strings.ToLower(list.target)
+	g := jsonGlobal{
+		Target:                  tgt,
+		MarketCapChange24hUSDPc: global.MarketCapChange24hUSDPc,
+		BTCDominance:            global.MarketCapPercentage["btc"],
+		ETHDominance:            global.MarketCapPercentage["eth"],
+	}
+	if mcap, ok := global.TotalMarketCap[tgt]; ok {
+		g.TotalMarketCap = &mcap
+	}
+	if vol, ok := global.TotalVolume[tgt]; ok {
+		g.TotalVolume = &vol
+	}
+	return g
+}
+
+// Prints a value as indented JSON on stdout.
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// Returns the price of a coin in the listing target, chosen as for the
+// price cell.
+func jsonCoinPrice(coin cgapi.CGCoinSingleton, list listing) jsonPrice {
+	p := jsonPrice{Target: strings.ToLower(list.target), Change24hPc: coinChange24h(coin, list)}
+	last, ok := coinPrice(coin, list)
+	if !ok {
+		return p
+	}
+	p.Price = &last
+	if t, ok := selectTicker(coin.Tickers, list); ok {
+		p.Source = "ticker"
+		p.Stale = tickerIsStale(t, list.staleAfter)
+	} else {
+		p.Source = "market"
+	}
+	return p
+}
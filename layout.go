@@ -0,0 +1,133 @@
+// layout.go
+// Adaptive layout fits the columns of a listing to their content and to the
+// width of the terminal.
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"ccpc/cgapi"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/term"
+)
+
+const (
+	// cellPadding is the space kept around the content of a fitted cell.
+	cellPadding = 2
+	// compactWidth is the terminal width below which the compact layout is used.
+	compactWidth = 80
+)
+
+// columnPriority orders columns from most to least important. When a listing
+// is wider than the terminal, the least important columns are dropped first.
+var columnPriority = []string{"symbol", "price", "change24h", "alert", "holding", "name", "mcap", "vol", "rank", "change1h",
+	"change7d", "change30d", "updated", "highlow", "ath", "fdv", "supply", "blocktime"}
+
+// Returns the width of the terminal, or 0 if stdout is not a terminal.
+// The COLUMNS environment variable takes precedence.
+func terminalWidth() int {
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0
+	}
+	width, _, err := term.GetSize(fd)
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// Returns the number of terminal cells a string occupies, counting wide
+// characters as two cells and combining characters as none.
+func textWidth(str string) int {
+	return uniseg.StringWidth(str)
+}
+
+// Generate tickers for coins, or print them as JSON along with the global
+// market data returned by printGlobalHeader. Adaptive listings are first
+// fitted to the coins and the terminal.
+func renderCoins(coins []cgapi.CGCoinSingleton, list listing, global *cgapi.CGGlobalData) {
+	if list.jsonOutput {
+		printJSONListing(coins, list, global)
+		return
+	}
+	if list.adaptive {
+		list = fitLayout(coins, list, terminalWidth())
+	}
+	for _, coin := range coins {
+		generateCoinTicker(coin, list)
+	}
+}
+
+// Returns the listing with its column widths fitted to the widest cell of
+// each column, and decimal aligned columns fitted to the widest whole and
+// fractional parts. Widths given with --columns are kept. While the listing is
+// wider than the terminal, the least important column is dropped, down to
+// a single column. Terminals narrower than compactWidth get the compact layout.
+func fitLayout(coins []cgapi.CGCoinSingleton, list listing, termWidth int) listing {
+	if termWidth < compactWidth {
+		list.compact = true
+	}
+	specs := list.layout()
+	total := 0
+	for i, spec := range specs {
+		w, whole, frac := 0, 0, 0
+		for _, coin := range coins {
+			if len(coin.Symbol) < 1 {
+				continue
+			}
+			str, _ := listingColumns[spec.name].cell(coin, spec.listing(list.forCoin(coin)))
+			if sw := textWidth(str) + cellPadding; sw > w {
+				w = sw
+			}
+			dot := strings.Index(str, list.numbers.decimal)
+			if dot < 0 {
+				dot = len(str)
+			}
+			if ww := textWidth(str[:dot]); ww > whole {
+				whole = ww
+			}
+			if fw := textWidth(str[dot:]); fw > frac {
+				frac = fw
+			}
+		}
+		if spec.align == "decimal" {
+			specs[i].decimal = whole + cellPadding/2
+			w = whole + frac + cellPadding
+		}
+		if !spec.fixed && w > 0 {
+			specs[i].width = w
+		}
+		total += specs[i].width
+	}
+	// Each ticker ends with a space before the newline.
+	for total+1 > termWidth && len(specs) > 1 {
+		drop := 0
+		for i, spec := range specs {
+			if columnRank(spec.name) > columnRank(specs[drop].name) {
+				drop = i
+			}
+		}
+		total -= specs[drop].width
+		specs = append(specs[:drop], specs[drop+1:]...)
+	}
+	list.columns = specs
+	return list
+}
+
+// Returns the position of a column in columnPriority.
+func columnRank(name string) int {
+	for i, n := range columnPriority {
+		if n == name {
+			return i
+		}
+	}
+	return len(columnPriority)
+}
@@ -0,0 +1,111 @@
+// logging.go
+// With --log-file, API requests and cache lookups are logged with log/slog
+// as text or JSON lines, for finding out what went wrong after the fact.
+// Nothing is logged otherwise.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// logger is replaced by openLog when --log-file is set.
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// logFile is the file logged to, closed by closeLog.
+var logFile *os.File
+
+// secretParams are query parameters which are redacted in logged URLs.
+var secretParams = []string{"key", "token", "secret", "password", "auth"}
+
+// Opens a log file, or stderr for "-", with a text or json format and a
+// minimum level of debug, info, warn or error.
+func openLog(path, format, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return errors.New("unknown log level '" + level + "'")
+	}
+	var w io.Writer = os.Stderr
+	if path != "-" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		logFile, w = file, file
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		logger = slog.New(slog.NewTextHandler(w, opts))
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(w, opts))
+	default:
+		closeLog()
+		return errors.New("unknown log format '" + format + "'; use text or json")
+	}
+	return nil
+}
+
+// Flushes and closes the log file, if there is one.
+func closeLog() {
+	if logFile != nil {
+		logFile.Sync()
+		logFile.Close()
+		logFile = nil
+	}
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// Logs a finished API request. Failed requests are logged as warnings.
+func logRequest(URL string, status, bytes int, latency time.Duration, err error) {
+	attrs := []any{
+		slog.String("endpoint", redactURL(URL)),
+		slog.Duration("latency", latency),
+		slog.Int("status", status),
+		slog.Int("bytes", bytes),
+	}
+	if err != nil {
+		logger.Warn("request failed", append(attrs, slog.String("error", redact(err.Error(), URL)))...)
+		return
+	}
+	logger.Info("request", attrs...)
+}
+
+// Logs a cache lookup, which is a hit if fresh data was found.
+func logCache(name string, hit bool) {
+	logger.Info("cache", slog.String("name", name), slog.Bool("hit", hit))
+}
+
+// Returns a URL with the user info and the values of secret query
+// parameters replaced.
+func redactURL(URL string) string {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return "(unparsable URL)"
+	}
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+	q := u.Query()
+	for name := range q {
+		for _, secret := range secretParams {
+			if strings.Contains(strings.ToLower(name), secret) {
+				q.Set(name, "REDACTED")
+				break
+			}
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Returns a message with a URL in it redacted.
+func redact(msg, URL string) string {
+	return strings.ReplaceAll(msg, URL, redactURL(URL))
+}
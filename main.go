@@ -3,17 +3,14 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"log"
 	"os/exec"
-	"os/signal"
 	"runtime"
 	"strconv"
-	"syscall"
 	"time"
-	"unicode/utf8"
 
 	"fmt"
 	"io/ioutil"
@@ -22,16 +19,23 @@ import (
 	"sort"
 	"strings"
 
+	"ccpc/bnapi"
 	"ccpc/cgapi"
 
 	"github.com/gookit/color"
 	flag "github.com/ogier/pflag"
+	"github.com/rivo/uniseg"
+	"golang.org/x/term"
 )
 
 const (
 	userAgent string = "ccpc, https://github.com/oishiiburger/ccpc"
+	ellipsis  string = "…"
 )
 
+// version is set when building, with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
 // target is used to set the currency for comparison.
 type target struct {
 	id string
@@ -39,18 +43,51 @@ type target struct {
 
 // Listing defines included elements in a possible listing.
 type listing struct {
+	adaptive         bool
+	alerts           bool
+	alertWidth       int
+	ath              bool
+	athWidth         int
 	blockTIM         bool
 	blockTIMWidth    int
+	change1h         bool
+	change7d         bool
+	change30d        bool
+	changeWidth      int
+	coinEntries      map[string]watchEntry
 	color            bool
+	columns          []columnSpec
+	compact          bool
 	errWidth         int
+	fdv              bool
+	fdvWidth         int
+	global           bool
+	highLow          bool
+	highLowWidth     int
+	holdings         bool
+	holdingWidth     int
+	jsonOutput       bool
 	lastUpdated      bool
 	lastUpdatedWidth int
+	marketCap        bool
+	marketCapWidth   int
+	maxTickerAge     time.Duration
+	minTrust         string
 	name             bool
 	nameWidth        int
+	numbers          numberFormat
 	priceWidth       int
+	rank             bool
+	rankWidth        int
+	resolveNames     bool
+	staleAfter       time.Duration
+	supply           bool
+	supplyWidth      int
 	symbol           bool
 	symbolWidth      int
 	target           string
+	targets          []string
+	theme            theme
 	volume           bool
 	volumeWidth      int
 }
@@ -58,11 +95,20 @@ type listing struct {
 // DefaultListingWidths defines the default field widths for a listing.
 func defaultListingWidths() listing {
 	self := listing{}
+	self.alertWidth = 22
+	self.athWidth = 26
 	self.blockTIMWidth = 11
+	self.changeWidth = 13
 	self.errWidth = 38
+	self.fdvWidth = 18
+	self.highLowWidth = 28
+	self.holdingWidth = 22
 	self.lastUpdatedWidth = 27
+	self.marketCapWidth = 18
 	self.nameWidth = 25
 	self.priceWidth = 28
+	self.rankWidth = 9
+	self.supplyWidth = 26
 	self.symbolWidth = 9
 	self.volumeWidth = 18
 	return self
@@ -76,6 +122,10 @@ func defaultListing() listing {
 	self.target = "USD"
 	self.lastUpdated = true
 	self.color = true
+	self.numbers = numberFormats["en"]
+	self.theme = defaultTheme()
+	self.maxTickerAge = 24 * time.Hour
+	self.staleAfter = time.Hour
 	return self
 }
 
@@ -89,13 +139,23 @@ func maxListing() listing {
 	self.color = true
 	self.blockTIM = true
 	self.volume = true
+	self.rank = true
+	self.marketCap = true
+	self.fdv = true
+	self.highLow = true
+	self.ath = true
+	self.supply = true
+	self.change1h = true
+	self.change7d = true
+	self.change30d = true
 	return self
 }
 
 // Entry point handles Args and flags
 func main() {
 	var listingProps listing = defaultListing()
-	var symbolsFile []string
+	var listingFltr listingFilter
+	var symbolsFile []watchEntry
 
 	// Set usage message
 	flag.Usage = func() {
@@ -105,6 +165,16 @@ func main() {
 		fmt.Println("https://github.com/oishiiburger/ccpc")
 		fmt.Print("Powered by CoinGecko API.\n\n")
 		fmt.Println("Usage: ccpc symbol(s) [options]")
+		fmt.Println("       ccpc markets symbol [options]")
+		fmt.Println("       ccpc serve [symbol(s)] [options]")
+		fmt.Println("       ccpc top|gainers|losers [N] [options]")
+		fmt.Printf("         (gainers and losers are among the top %d coins by market cap)\n", moversUniverse)
+		fmt.Println("       ccpc trending [options]")
+		fmt.Println("       ccpc search query [options]")
+		fmt.Println("       ccpc global [options]")
+		fmt.Println("       ccpc get symbol [--field=price|change24h|volume|marketcap]")
+		fmt.Println("       ccpc watch add|rm symbol[:target]... [--watchlist=name] [--note=text]")
+		fmt.Println("       ccpc watch ls|show [name]")
 		fmt.Println("Options:")
 		flag.PrintDefaults()
 	}
@@ -114,48 +184,168 @@ func main() {
 	blkPtr := flag.BoolP("block-time", "b", false, "Includes block time in the listing, if available.")
 	bwtPtr := flag.BoolP("no-color", "c", false, "Disables output colors.")
 	durPtr := flag.UintP("update-duration", "d", 30, "Sets the duraton (seconds) for the rate of update mode.")
-	filPtr := flag.StringP("symbols-from-file", "f", "", "Loads a list of symbols from a text file, one symbol per line.")
+	filPtr := flag.StringP("symbols-from-file", "f", "", "Loads symbols from a text, CSV or JSON file, or from stdin for -.")
 	maxPtr := flag.BoolP("maximum", "m", false, "Yields maximum detail listings for the selected coins.")
 	namPtr := flag.BoolP("no-name", "n", false, "Omits coin name in the listing.")
 	pngPtr := flag.BoolP("ping", "p", false, "Pings the Coin Gecko API and shows the message.")
-	tgtPtr := flag.StringP("target", "t", "usd", "Determines the target currency for comparison (e.g. usd, jpy).")
+	qutPtr := flag.BoolP("quiet", "q", false, "Shows only errors on stderr, without progress or warnings.")
+	strPtr := flag.BoolP("stream", "s", false, "Streams live prices from the Binance WebSocket feed instead of polling.")
+	tgtPtr := flag.StringP("target", "t", "usd", "Determines the target currencies for comparison (e.g. usd, or usd,jpy,btc).")
 	timPtr := flag.BoolP("no-time", "z", false, "Omits last update time in the listing.")
 	updPtr := flag.BoolP("update-mode", "u", false, "Updates the same set of tickers every no. of seconds.")
 	volPtr := flag.BoolP("volume", "v", false, "Includes coin volume in the listing, if available.")
+	athPtr := flag.Bool("ath", false, "Includes the all-time high and the distance from it in the listing.")
+	cttPtr := flag.Uint("cache-ttl", 60, "Sets how many seconds the get command reuses a cached value.")
+	c1hPtr := flag.Bool("change-1h", false, "Includes the 1h price change in the listing.")
+	c30Ptr := flag.Bool("change-30d", false, "Includes the 30d price change in the listing.")
+	c7dPtr := flag.Bool("change-7d", false, "Includes the 7d price change in the listing.")
+	colPtr := flag.String("columns", "", "Chooses and orders the listing columns, e.g. symbol,name:30:left,price:28:right.")
+	fdvPtr := flag.Bool("fdv", false, "Includes the fully diluted valuation in the listing.")
+	fldPtr := flag.String("field", "price", "Sets the field printed by the get command: price, change24h, volume or marketcap.")
+	fxwPtr := flag.Bool("fixed-widths", false, "Uses fixed column widths instead of fitting them to the terminal.")
+	fcoPtr := flag.Bool("force-color", false, "Keeps output colors when NO_COLOR is set or stdout is not a terminal.")
+	glbPtr := flag.Bool("global", false, "Shows a global market overview above the listing.")
+	grdPtr := flag.Bool("gradient", false, "Scales the color intensity of price changes with the size of the move.")
+	hlwPtr := flag.Bool("high-low", false, "Includes the 24h high and low in the listing.")
+	jsnPtr := flag.Bool("json", false, "Prints listings as JSON instead of a table.")
 	lcPtr := flag.Bool("list-coins", false, "Displays a listing of all known coins.")
 	lmPtr := flag.Bool("list-currencies", false, "Displays a listing of all known currencies.")
+	ltmPtr := flag.Bool("list-themes", false, "Displays a listing of all known color themes.")
+	lsnPtr := flag.String("listen", "localhost:8080", "Serves the JSON price API on this address in serve mode.")
+	lclPtr := flag.String("locale", "", "Formats numbers for a locale, e.g. de_DE (default from LC_ALL, LC_NUMERIC or LANG).")
+	lfiPtr := flag.String("log-file", "", "Logs API requests and cache lookups to this file, or to stderr for -.")
+	lfmPtr := flag.String("log-format", "text", "Sets the log format: text or json.")
+	llvPtr := flag.String("log-level", "info", "Sets the lowest level logged: debug, info, warn or error.")
+	mcpPtr := flag.Bool("market-cap", false, "Includes the market cap in the listing.")
+	mktPtr := flag.String("market-target", "", "Shows only markets trading against this currency (e.g. usdt, btc).")
+	metPtr := flag.String("metrics", "", "Serves Prometheus metrics on this address (e.g. :9101) in serve mode.")
+	msrPtr := flag.String("market-sort", "volume", "Sorts markets by volume or spread.")
+	rtlPtr := flag.Uint("rate-limit", 30, "Limits Coin Gecko API requests per minute in serve mode (0 disables).")
+	trsPtr := flag.String("min-trust", "", "Skips exchange tickers below this trust score (green, yellow, red).")
+	agePtr := flag.Uint("max-ticker-age", 1440, "Skips exchange tickers older than this many minutes (0 disables).")
+	stlPtr := flag.Uint("stale-after", 60, "Marks prices older than this many minutes with a '*' (0 disables).")
+	surPtr := flag.String("stream-url", bnapi.StreamURL, "Sets the combined stream URL used by stream mode.")
+	mprPtr := flag.Float64("max-price", 0, "Shows only coins priced at or below this in the target currency.")
+	mchPtr := flag.Float64("min-change", 0, "Shows only coins whose 24h change is at least this percentage.")
+	mvlPtr := flag.Float64("min-volume", 0, "Shows only coins with at least this 24h volume in the target currency.")
+	notPtr := flag.String("note", "", "Adds a note to the coins added with watch add.")
+	rnkPtr := flag.Bool("rank", false, "Includes the market cap rank in the listing.")
+	rslPtr := flag.Bool("resolve-names", false, "Resolves unknown symbols which are coin names or IDs (e.g. ethereum).")
+	sapPtr := flag.Bool("search-api", false, "Also searches the Coin Gecko API, adding coin names and market cap ranks.")
+	srtPtr := flag.String("sort", "", "Sorts listings by price, change, volume, name or marketcap; append :asc or :desc.")
+	supPtr := flag.Bool("supply", false, "Includes the circulating, total and max supply in the listing.")
+	thmPtr := flag.String("theme", "default", "Sets the color theme (default, light, high-contrast, colorblind or one from config).")
+	topPtr := flag.Uint("top", 0, "Shows only the first N listings, after sorting.")
+	vrbPtr := flag.Bool("verbose", false, "Also shows each request and how long it took on stderr.")
+	verPtr := flag.Bool("version", false, "Shows the ccpc version and the date of its coin and currency tables.")
+	wlsPtr := flag.String("watchlist", defaultWatchlist, "Names the watchlist for the watch command, or lists its coins.")
 	flag.Parse()
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	if *verPtr {
+		fmt.Println("ccpc " + version)
+		fmt.Printf("Coin and currency tables (%s): %d coins, %d currencies.\n", cgapi.TablesVersion,
+			len(cgapi.CGCoinURLs), len(cgapi.SupportedCurrencies))
+		os.Exit(0)
+	}
+	if *qutPtr && *vrbPtr {
+		usrMessage("Cannot be both quiet and verbose.", true)
+	} else if *qutPtr {
+		outputLevel = quietLevel
+	} else if *vrbPtr {
+		outputLevel = verboseLevel
+	}
+	if *lfiPtr != "" {
+		if err := openLog(*lfiPtr, *lfmPtr, *llvPtr); err != nil {
+			usrMessage("Could not open the log: "+err.Error()+".", true)
+		}
+		defer closeLog()
+	}
+	// The get command prints nothing but its value, and serve runs unattended
+	initProgress(flag.Arg(0) != "get" && flag.Arg(0) != "serve")
+	// Every command runs with ctx, which SIGINT and SIGTERM cancel
+	ctx, stop := signalContext()
+	defer stop()
 	// maxListing is copied over listingProperties, so it must be first
 	if *maxPtr {
 		listingProps = maxListing()
 	}
+	listingProps.maxTickerAge = time.Duration(*agePtr) * time.Minute
+	if *athPtr {
+		listingProps.ath = true
+	}
 	if *blkPtr {
 		listingProps.blockTIM = true
 	}
-	if *bwtPtr {
+	if *bwtPtr || (!*fcoPtr && (os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(os.Stdout.Fd())))) {
 		listingProps.color = false
+		color.Enable = false
+	} else if *fcoPtr {
+		color.ForceOpenColor()
 	}
-	if *filPtr != "" {
-		file, err := os.Open(*filPtr)
+	if *c1hPtr {
+		listingProps.change1h = true
+	}
+	if *c7dPtr {
+		listingProps.change7d = true
+	}
+	if *c30Ptr {
+		listingProps.change30d = true
+	}
+	if *colPtr != "" {
+		columns, err := parseColumns(*colPtr, listingProps)
 		if err != nil {
-			usrMessage("Could not load specified symbol file.", true, listingProps)
-		}
-		s := bufio.NewScanner(file)
-		for s.Scan() {
-			symbolsFile = append(symbolsFile, s.Text())
+			usrMessage("Could not use columns: "+err.Error()+".", true, listingProps)
 		}
+		listingProps.columns = columns
+	}
+	if *fdvPtr {
+		listingProps.fdv = true
+	}
+	if *glbPtr {
+		listingProps.global = true
+	}
+	listingProps.adaptive = !*fxwPtr && terminalWidth() > 0
+	if *hlwPtr {
+		listingProps.highLow = true
+	}
+	if *jsnPtr {
+		listingProps.jsonOutput = true
+	}
+	if nf, ok := localeNumberFormat(*lclPtr); ok {
+		listingProps.numbers = nf
+	} else if *lclPtr != "" {
+		usrMessage("Unknown locale: "+*lclPtr+"; using default.", false, listingProps)
 	}
 	if *lcPtr {
 		listTableKeys(cgapi.CGCoinURLs, "coins")
 	}
 	if *lmPtr {
-		listTableKeys(cgapi.MonetarySymbols, "currencies", cgapi.MonetaryNames)
+		listCurrencies()
+	}
+	if *ltmPtr {
+		listThemes(listingProps)
+	}
+	if *mcpPtr {
+		listingProps.marketCap = true
+	}
+	if setFlags["min-change"] {
+		listingFltr.minChange = mchPtr
+	}
+	if setFlags["max-price"] {
+		listingFltr.maxPrice = mprPtr
+	}
+	if setFlags["min-volume"] {
+		listingFltr.minVolume = mvlPtr
 	}
 	if *namPtr {
 		listingProps.name = false
 	}
 	if *pngPtr {
-		res, err := httpRequest(cgapi.APIPingURL, userAgent)
+		res, err := httpRequest(ctx, cgapi.APIPingURL, userAgent)
+		exitIfInterrupted(ctx)
 		if err != nil {
 			usrMessage("Coin Gecko API is not responding.", true, listingProps)
 		}
@@ -163,161 +353,337 @@ func main() {
 		json.Unmarshal(res, &ping)
 		usrMessage("API has responded: "+ping.PingMsg, false, listingProps)
 	}
-	if *tgtPtr != "" {
-		tgt := strings.ToUpper(*tgtPtr)
-		if len(cgapi.MonetarySymbols[tgt]) > 0 {
-			listingProps.target = tgt
-		} else {
-			usrMessage("Unknown target currency: "+tgt+"; using default.", false, listingProps)
+	if *rnkPtr {
+		listingProps.rank = true
+	}
+	if *rslPtr {
+		listingProps.resolveNames = true
+	}
+	if *srtPtr != "" && !listingFltr.parseSort(*srtPtr) {
+		usrMessage("Unknown sort '"+*srtPtr+"'; use price, change, volume, name or marketcap.", true, listingProps)
+	}
+	listingProps.staleAfter = time.Duration(*stlPtr) * time.Minute
+	if *supPtr {
+		listingProps.supply = true
+	}
+	if setFlags["target"] {
+		var targets, unknown []string
+		for _, t := range strings.Split(*tgtPtr, ",") {
+			tgt := strings.ToUpper(strings.TrimSpace(t))
+			if !knownCurrency(tgt) {
+				unknown = append(unknown, tgt)
+			} else if !containsString(targets, tgt) {
+				targets = append(targets, tgt)
+			}
+		}
+		for _, tgt := range unknown {
+			if len(targets) == 0 {
+				usrMessage("Unknown target currency: "+tgt+"; using default.", false, listingProps)
+			} else {
+				usrMessage("Unknown target currency: "+tgt+"; leaving it out.", false, listingProps)
+			}
 		}
+		if len(targets) > 0 {
+			listingProps.target = targets[0]
+			listingProps.targets = targets
+		}
+	}
+	if th, err := loadTheme(*thmPtr, *grdPtr); err == nil {
+		listingProps.theme = th
+	} else {
+		usrMessage("Could not use theme: "+err.Error()+"; using default.", false, listingProps)
 	}
 	if *timPtr {
 		listingProps.lastUpdated = false
 	}
+	listingFltr.top = int(*topPtr)
+	if *trsPtr != "" {
+		trust := strings.ToLower(*trsPtr)
+		if _, ok := cgapi.TrustScoreRanks[trust]; !ok {
+			usrMessage("Unknown trust score '"+trust+"'; use green, yellow or red.", true, listingProps)
+		}
+		listingProps.minTrust = trust
+	}
 	if *volPtr {
 		listingProps.volume = true
 	}
+	// The symbols file's coins are resolved as set by --resolve-names
+	if *filPtr != "" {
+		entries, err := readSymbolsFile(*filPtr, listingProps)
+		if err != nil {
+			usrMessage("Could not load specified symbol file: "+err.Error()+".", true, listingProps)
+		}
+		symbolsFile = entries
+	}
 	// --all needs other listingProperties ready
 	if *allPtr {
-		if *updPtr {
-			usrMessage("Cannot yield all listings in update mode.", true, listingProps)
+		if *updPtr || *strPtr {
+			usrMessage("Cannot yield all listings in update or stream mode.", true, listingProps)
 		} else {
+			global := printGlobalHeader(ctx, listingProps)
+			var coins []cgapi.CGCoinSingleton
 			keys := mapToSortedStrings(cgapi.CGCoinURLs)
+			startCount("Fetching coins", len(keys))
 			for key := 0; key < len(keys); key++ {
-				res, _ := httpRequest(cgapi.CGCoinURL+cgapi.CGCoinURLs[keys[key]], userAgent)
+				res, _ := httpRequest(ctx, cgapi.CGCoinURL+cgapi.CGCoinURLs[keys[key]], userAgent)
+				exitIfInterrupted(ctx)
+				countStep()
 				var coin cgapi.CGCoinSingleton
 				json.Unmarshal(res, &coin)
-				generateCoinTicker(coin, listingProps)
+				if listingFltr.active() || listingProps.adaptive || listingProps.jsonOutput {
+					coins = append(coins, coin)
+				} else {
+					generateCoinTicker(coin, listingProps)
+				}
 			}
+			endCount()
+			renderCoins(filterCoins(coins, listingProps, listingFltr), listingProps, global)
 		}
 	}
 
 	// CLI Args handling
 	if len(os.Args) == 1 {
 		flag.Usage()
+	} else if flag.Arg(0) == "serve" {
+		args := append(entrySymbols(symbolsFile), flag.Args()[1:]...)
+		runServe(ctx, args, listingProps, *lsnPtr, *metPtr, *durPtr, *rtlPtr)
+	} else if flag.Arg(0) == "global" {
+		runGlobal(ctx, listingProps)
+	} else if flag.Arg(0) == "top" {
+		runTop(ctx, flag.Args()[1:], listingProps, listingFltr)
+	} else if flag.Arg(0) == "trending" {
+		runTrending(ctx, listingProps, listingFltr)
+	} else if flag.Arg(0) == "gainers" || flag.Arg(0) == "losers" {
+		runMovers(ctx, flag.Args()[1:], flag.Arg(0) == "gainers", listingProps, listingFltr)
+	} else if flag.Arg(0) == "search" {
+		runSearch(ctx, flag.Args()[1:], *sapPtr, listingProps, listingFltr)
+	} else if flag.Arg(0) == "get" {
+		runGet(ctx, flag.Args()[1:], *fldPtr, time.Duration(*cttPtr)*time.Second, listingProps)
+	} else if flag.Arg(0) == "watch" {
+		runWatch(flag.Args()[1:], *wlsPtr, *notPtr, listingProps)
+	} else if flag.Arg(0) == "markets" {
+		filter := marketFilter{
+			target:   *mktPtr,
+			minTrust: listingProps.minTrust,
+			sortBy:   strings.ToLower(*msrPtr),
+		}
+		runMarkets(ctx, flag.Args()[1:], filter, listingProps)
 	} else if !*allPtr {
-		var args []string
-		if len(symbolsFile) > 0 {
-			for _, s := range symbolsFile {
-				args = append(args, s)
-			}
+		entries := symbolsFile
+		if setFlags["watchlist"] {
+			entries = append(entries, mustLoadWatchlist(*wlsPtr, listingProps).entries()...)
 		}
 		for _, a := range flag.Args() {
-			args = append(args, a)
+			entries = append(entries, watchEntry{symbol: a})
+		}
+		if *strPtr {
+			runStream(ctx, entries, listingProps, *surPtr)
+		} else {
+			runOnceOrUpdate(ctx, entries, listingProps, listingFltr, *updPtr, *durPtr)
 		}
-		runOnceOrUpdate(args, listingProps, *updPtr, *durPtr)
 	}
+	exitIfInterrupted(ctx)
 }
 
 // Will run continuously when in update mode.
-func runOnceOrUpdate(args []string, list listing, upd bool, dur uint) {
-	clearCmd := make(map[string]func())
-update:
-	if upd {
-		sig := make(chan os.Signal, 1)
-		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
-		go func() {
-			<-sig
-			os.Exit(0)
-		}()
-
-		clearCmd["windows"] = func() {
-			clear := exec.Command("cmd", "/c", "cls")
-			clear.Stdout = os.Stdout
-			clear.Run()
-		}
-		clearCmd["linux"] = func() {
-			clear := exec.Command("clear")
-			clear.Stdout = os.Stdout
-			clear.Run()
-		}
-		_, ok := clearCmd[runtime.GOOS]
-		if !ok {
-			usrMessage("No screen clear function for this platform.", true)
-		}
-		clearCmd[runtime.GOOS]()
+// Coins with their own target are listed in it, and holdings and alerts
+// are shown when any coin has a quantity or alert threshold.
+// Updates start every dur seconds however long the listing takes, until
+// ctx is canceled.
+func runOnceOrUpdate(ctx context.Context, entries []watchEntry, list listing, fltr listingFilter, upd bool, dur uint) {
+	list.coinEntries = make(map[string]watchEntry)
+	for _, e := range entries {
+		if e.quantity != 0 {
+			list.holdings = true
+		}
+		if e.alertAbove != 0 || e.alertBelow != 0 {
+			list.alerts = true
+		}
+	}
+	if !upd {
+		listCoins(ctx, entries, list, fltr)
+		return
+	}
+	if dur == 0 {
+		usrMessage("The update duration must be at least one second.", true, list)
+	}
+	ticker := time.NewTicker(time.Duration(dur) * time.Second)
+	defer ticker.Stop()
+	for {
+		clearScreen()
 		d := fmt.Sprint(dur)
 		usrMessage("You are running ccpc in update mode. Will update every "+d+" seconds.", false, list)
-	}
-	for _, arg := range args {
-		var symb = arg
-		if cgapi.CGCoinURLs[symb] == "" {
-			symb = strings.ToUpper(arg)
+		listCoins(ctx, entries, list, fltr)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
-		if cgapi.CGCoinURLs[symb] == "" {
-			usrMessage("Unknown coin symbol '"+symb+"'", false, list)
+	}
+}
+
+// Lists the coins of entries once. It stops early if ctx is canceled.
+// With several targets, the prices in the other targets are fetched for
+// all coins in one request once the coins are fetched.
+func listCoins(ctx context.Context, entries []watchEntry, list listing, fltr listingFilter) {
+	global := printGlobalHeader(ctx, list)
+	var coins []cgapi.CGCoinSingleton
+	startCount("Fetching coins", len(entries))
+	for _, e := range entries {
+		symb, id := lookupCoin(e.symbol, list)
+		if id == "" {
+			usrMessage(unknownSymbolMessage(symb), false, list)
+			countStep()
 		} else {
-			res, err := httpRequest(cgapi.CGCoinURL+cgapi.CGCoinURLs[symb], userAgent)
-			if err != nil {
-				usrMessage("HTTP request did not complete successfully.", true, list)
+			list.coinEntries[id] = e
+			res, err := httpRequest(ctx, cgapi.CGCoinURL+id, userAgent)
+			if ctx.Err() != nil {
+				endCount()
+				return
+			} else if err != nil {
+				requestFailed(ctx, list)
 			}
+			countStep()
 			var coin cgapi.CGCoinSingleton
 			json.Unmarshal(res, &coin)
-			generateCoinTicker(coin, list)
+			if fltr.active() || list.adaptive || list.jsonOutput || len(list.targets) > 1 {
+				coins = append(coins, coin)
+			} else {
+				generateCoinTicker(coin, list)
+			}
 		}
 	}
-	if upd {
-		time.Sleep(time.Duration(dur) * time.Second)
-		goto update
+	endCount()
+	if err := addTargetPrices(ctx, coins, list); err != nil {
+		usrMessage("Could not load prices in the other targets.", false, list)
+	}
+	renderCoins(filterCoins(coins, list, fltr), list, global)
+}
+
+// Clears the terminal screen.
+func clearScreen() {
+	clearCmd := make(map[string]func())
+	clearCmd["windows"] = func() {
+		clear := exec.Command("cmd", "/c", "cls")
+		clear.Stdout = os.Stdout
+		clear.Run()
+	}
+	clearCmd["linux"] = func() {
+		clear := exec.Command("clear")
+		clear.Stdout = os.Stdout
+		clear.Run()
+	}
+	_, ok := clearCmd[runtime.GOOS]
+	if !ok {
+		usrMessage("No screen clear function for this platform.", true)
+	}
+	clearCmd[runtime.GOOS]()
+}
+
+// Looks up a symbol in CGCoinURLs, falling back to its upper case form.
+// Returns the symbol as found and its coin ID, which is empty if unknown.
+func coinID(arg string) (symb, id string) {
+	symb = arg
+	if cgapi.CGCoinURLs[symb] == "" {
+		symb = strings.ToUpper(arg)
 	}
+	return symb, cgapi.CGCoinURLs[symb]
 }
 
 // Generate a coin ticker.
 func generateCoinTicker(coin cgapi.CGCoinSingleton, list listing) {
-	var tickerIdx int
 	if len(coin.Symbol) < 1 {
 		// usrMessage("Coin symbol was not successfully loaded.", true, list)
 	} else {
-		tPrint(coin.Symbol, list.symbol, list, color.BgBlue, list.symbolWidth)
-		tPrint(coin.Name, list.name, list, color.FgBlue, list.nameWidth)
-		if len(coin.Tickers) > 0 { // check to make sure this coin has a ticker
-			for tickerIdx, t := range coin.Tickers {
-				if t.Target == list.target {
-					var per string
-					if coin.MarketData.PriceChange24hPc != 0 {
-						if coin.MarketData.PriceChange24hPc >= 0 {
-							per = "+"
-						}
-						per = "(" + per + fmt.Sprintf("%3.2f", coin.MarketData.PriceChange24hPc) + "%/24h)"
-					}
-					if coin.MarketData.PriceChange24h >= 0 {
-						tPrint(cgapi.MonetarySymbols[list.target]+fmt.Sprintf("%.2f", coin.Tickers[tickerIdx].Last)+
-							" "+per, true, list, color.BgGreen, list.priceWidth)
-					} else {
-						tPrint(cgapi.MonetarySymbols[list.target]+fmt.Sprintf("%.2f", coin.Tickers[tickerIdx].Last)+
-							" "+per, true, list, color.BgRed, list.priceWidth)
-					}
-					break
-				}
-				if tickerIdx == len(coin.Tickers)-1 {
-					tPrint("no price", true, list, color.BgYellow, list.priceWidth)
-				}
-			}
-		} else {
-			tPrint("no price", true, list, color.BgYellow, list.priceWidth)
+		list = list.forCoin(coin)
+		for _, spec := range list.layout() {
+			str, col := listingColumns[spec.name].cell(coin, spec.listing(list))
+			cPrint(str, list, col, spec)
 		}
-		tm, err := time.Parse(time.RFC3339Nano, coin.LastUpdated)
-		if err != nil {
-			usrMessage("Could not parse time string from API.", true)
+	}
+	fmt.Println(" ")
+}
+
+// Returns the price shown for a coin: the last price of the selected exchange
+// ticker or, when no ticker at all is against the target, the market data
+// price. Coins whose tickers against the target are all stale or untrusted
+// have no price.
+func coinPrice(coin cgapi.CGCoinSingleton, list listing) (float64, bool) {
+	if t, ok := selectTicker(coin.Tickers, list); ok {
+		return t.Last, true
+	}
+	if hasTickerFor(coin.Tickers, list.target) {
+		return 0, false
+	}
+	p, ok := coin.MarketData.CurrentPrice[strings.ToLower(list.target)]
+	return p, ok
+}
+
+// Reports whether a coin's price is the market data price although it was
+// fetched with exchange tickers, because none of them is against the target.
+func marketPriceFallback(coin cgapi.CGCoinSingleton, list listing) bool {
+	return len(coin.Tickers) > 0 && !hasTickerFor(coin.Tickers, list.target)
+}
+
+// Reports whether any ticker is against a target.
+func hasTickerFor(tickers []cgapi.CGTicker, target string) bool {
+	for _, t := range tickers {
+		if t.Target == target {
+			return true
 		}
-		tPrint("UPD:"+tm.Format(time.RFC822), list.lastUpdated, list, color.BgDarkGray, list.lastUpdatedWidth)
-		if len(coin.Tickers) > 0 {
-			tPrint(coin.Tickers[tickerIdx].Volume, list.volume, list, color.BgDarkGray, list.volumeWidth, "VOL:")
-		} else {
-			tPrint("no volume", list.volume, list, color.BgDarkGray, list.volumeWidth, "VOL:")
+	}
+	return false
+}
+
+// Returns the 24h price change in percent of a coin in the listing target,
+// or in USD if the change in the target is not known.
+func coinChange24h(coin cgapi.CGCoinSingleton, list listing) float64 {
+	if pc, ok := coin.MarketData.PriceChange24hCurPc[strings.ToLower(list.target)]; ok {
+		return pc
+	}
+	return coin.MarketData.PriceChange24hPc
+}
+
+// Returns the first ticker against the listing target which is neither below
+// the minimum trust score nor older than the maximum ticker age.
+func selectTicker(tickers []cgapi.CGTicker, list listing) (cgapi.CGTicker, bool) {
+	for _, t := range tickers {
+		if t.Target != list.target {
+			continue
+		}
+		if cgapi.TrustScoreRanks[t.TrustScore] < cgapi.TrustScoreRanks[list.minTrust] {
+			continue
 		}
-		tPrint(coin.BlockTimeInMinutes, list.blockTIM, list, color.BgDarkGray, list.blockTIMWidth, "BT:")
+		if age, ok := tickerAge(t); ok && list.maxTickerAge > 0 && age > list.maxTickerAge {
+			continue
+		}
+		return t, true
 	}
-	fmt.Println(" ")
+	return cgapi.CGTicker{}, false
+}
+
+// Returns how long ago a ticker was last updated, if its timestamp parses.
+func tickerAge(t cgapi.CGTicker) (time.Duration, bool) {
+	tm, err := time.Parse(time.RFC3339, t.Timestamp)
+	if err != nil {
+		return 0, false
+	}
+	return time.Since(tm), true
+}
+
+// Reports whether a ticker is older than the stale threshold.
+func tickerIsStale(t cgapi.CGTicker, staleAfter time.Duration) bool {
+	age, ok := tickerAge(t)
+	return ok && staleAfter > 0 && age > staleAfter
 }
 
 // Helper function for printing tickers.
-func tPrint(ifc interface{}, chk bool, lst listing, col color.Color, wid int, labl ...string) {
+func tPrint(ifc interface{}, chk bool, lst listing, col paint, wid int, labl ...string) {
 	if chk {
 		switch ifc.(type) {
 		case string:
 			if lst.color {
-				col.Print(cenTextInRange(ifc.(string), wid))
+				fmt.Print(lst.theme.render(col, cenTextInRange(ifc.(string), wid)))
 			} else {
 				fmt.Print(cenTextInRange(ifc.(string), wid))
 			}
@@ -336,7 +702,7 @@ func tPrint(ifc interface{}, chk bool, lst listing, col color.Color, wid int, la
 				form = "%f"
 			}
 			if lst.color {
-				col.Print(cenTextInRange(id+fmt.Sprintf(form, ifc), wid))
+				fmt.Print(lst.theme.render(col, cenTextInRange(id+fmt.Sprintf(form, ifc), wid)))
 			} else {
 				fmt.Print(cenTextInRange(id+fmt.Sprintf(form, ifc), wid))
 			}
@@ -344,11 +710,43 @@ func tPrint(ifc interface{}, chk bool, lst listing, col color.Color, wid int, la
 	}
 }
 
-// Performs an HTTP request and updates the user.
-func httpRequest(URL, userAgent string) (contents []byte, err error) {
-	fmt.Print("Fetching data...\r")
+// Helper function for printing the cells of a column.
+func cPrint(str string, lst listing, col paint, spec columnSpec) {
+	if spec.align == "decimal" {
+		at := spec.decimal
+		if at == 0 {
+			at = spec.width / 2
+		}
+		str = decAlignTextInRange(str, spec.width, at, lst.numbers.decimal)
+	} else {
+		str = alignTextInRange(str, spec.width, spec.align)
+	}
+	if lst.color {
+		fmt.Print(lst.theme.render(col, str))
+	} else {
+		fmt.Print(str)
+	}
+}
+
+// Performs an HTTP request, showing progress on stderr.
+// Responses other than 200 OK are returned as errors.
+func httpRequest(ctx context.Context, URL, userAgent string) (contents []byte, err error) {
+	defer spin()()
+	apiCounters.requests.Add(1)
+	start := time.Now()
+	status := 0
+	defer func() {
+		latency := time.Since(start)
+		logRequest(URL, status, len(contents), latency, err)
+		if err != nil {
+			apiCounters.errors.Add(1)
+			verboseMessage("GET %s failed after %v: %v", redactURL(URL), latency.Round(time.Millisecond), redact(err.Error(), URL))
+		} else {
+			verboseMessage("GET %s: %d bytes in %v", redactURL(URL), len(contents), latency.Round(time.Millisecond))
+		}
+	}()
 	cli := http.Client{}
-	req, err := http.NewRequest("GET", URL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -358,6 +756,13 @@ func httpRequest(URL, userAgent string) (contents []byte, err error) {
 		return nil, err
 	}
 	defer res.Body.Close()
+	status = res.StatusCode
+	if res.StatusCode == http.StatusTooManyRequests {
+		apiCounters.rateLimited.Add(1)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", URL, res.Status)
+	}
 	contents, err = ioutil.ReadAll(res.Body)
 	if err != nil {
 		return nil, err
@@ -367,14 +772,11 @@ func httpRequest(URL, userAgent string) (contents []byte, err error) {
 
 // Returns a string which is centered in the middle of the range.
 func cenTextInRange(str string, rng int) string {
-	if utf8.RuneCountInString(str) > rng {
-		if rng > 4 {
-			str = str[:rng-5] + "."
-		} else {
-			str = "."
-		}
+	str = truncToRange(str, rng)
+	diff := rng - textWidth(str)
+	if diff < 0 {
+		diff = 0
 	}
-	diff := rng - utf8.RuneCountInString(str)
 	buf := new(bytes.Buffer)
 	if diff%2 != 0 {
 		str = str + " "
@@ -389,6 +791,79 @@ func cenTextInRange(str string, rng int) string {
 	return buf.String()
 }
 
+// Returns a string which is aligned left, right or center in the range.
+func alignTextInRange(str string, rng int, align string) string {
+	str = truncToRange(str, rng)
+	diff := rng - textWidth(str)
+	if diff < 0 {
+		diff = 0
+	}
+	switch align {
+	case "left":
+		return str + strings.Repeat(" ", diff)
+	case "right":
+		return strings.Repeat(" ", diff) + str
+	}
+	return cenTextInRange(str, rng)
+}
+
+// Returns a string placed in the range so that its first decimal mark is
+// at column at, or its end is if it has none. Strings which cannot be
+// placed so are right aligned.
+func decAlignTextInRange(str string, rng, at int, mark string) string {
+	str = truncToRange(str, rng)
+	whole := str
+	if i := strings.Index(str, mark); i >= 0 {
+		whole = str[:i]
+	}
+	left := at - textWidth(whole)
+	right := rng - left - textWidth(str)
+	if left < 0 || right < 0 {
+		return alignTextInRange(str, rng, "right")
+	}
+	return strings.Repeat(" ", left) + str + strings.Repeat(" ", right)
+}
+
+// Returns a string shortened with an ellipsis to fit the range, leaving room
+// for padding. Grapheme clusters are never split, and wide characters such
+// as CJK count as two cells.
+func truncToRange(str string, rng int) string {
+	if textWidth(str) <= rng {
+		return str
+	}
+	limit := rng - cellPadding - textWidth(ellipsis)
+	if limit < 0 {
+		limit = rng - textWidth(ellipsis)
+	}
+	if limit < 0 {
+		return ""
+	}
+	buf := new(bytes.Buffer)
+	width := 0
+	state := -1
+	for rest := str; len(rest) > 0; {
+		var cluster string
+		var w int
+		cluster, rest, w, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		if width+w > limit {
+			break
+		}
+		buf.WriteString(cluster)
+		width += w
+	}
+	return buf.String() + ellipsis
+}
+
+// Reports whether a slice contains a string.
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
 // Takes a map and returns an sorted slice of strings.
 func mapToSortedStrings(mp map[string]string) []string {
 	var keys = make([]string, len(mp))
@@ -417,20 +892,26 @@ func listTableKeys(mp map[string]string, str string, more ...map[string]string)
 	}
 }
 
-// Give user an error message and sometimes exit.
+// Gives the user a message on stderr and sometimes exits.
+// Warnings are left out with --quiet.
 func usrMessage(str string, exit bool, lst ...listing) {
 	if len(lst) > 0 {
+		if !exit && outputLevel == quietLevel {
+			return
+		}
+		label, col := "attn!", paintWarn
 		if exit {
-			tPrint("error", true, lst[0], color.BgRed, 9)
-		} else {
-			tPrint("attn!", true, lst[0], color.BgYellow, 9)
+			label, col = "error", paintError
+		}
+		label, str = cenTextInRange(label, 9), cenTextInRange(str, len(str)+4)
+		if lst[0].color {
+			label, str = lst[0].theme.render(col, label), lst[0].theme.render(paintText, str)
 		}
-		tPrint(str, true, lst[0], color.FgDefault, len(str)+4)
+		fmt.Fprintln(os.Stderr, label+str)
 		if exit {
 			os.Exit(1)
 		}
 	} else {
 		log.Fatal(str)
 	}
-	fmt.Println()
 }
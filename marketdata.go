@@ -0,0 +1,94 @@
+// marketdata.go
+// Market data columns of the extended listing: rank, market cap, valuation,
+// 24h range, all-time high, supply and price changes.
+// Values the API leaves out or reports as zero are shown as n/a.
+
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"ccpc/cgapi"
+)
+
+func rankCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	if coin.MarketCapRank > 0 {
+		return "#" + strconv.Itoa(coin.MarketCapRank), paintAccent
+	}
+	return "unranked", paintInfo
+}
+
+func marketCapCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	return amountCell("MCAP:", list, coin.MarketData.MarketCap), paintInfo
+}
+
+func fdvCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	return amountCell("FDV:", list, coin.MarketData.FullyDilutedValuation), paintInfo
+}
+
+// Show the 24h high and low price.
+func highLowCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	tgt := strings.ToLower(list.target)
+	high, low := coin.MarketData.High24h[tgt], coin.MarketData.Low24h[tgt]
+	if high == 0 || low == 0 {
+		return "H/L:n/a", paintInfo
+	}
+	return "H/L:" + list.numbers.price(high, list.target) + "/" + list.numbers.price(low, list.target), paintInfo
+}
+
+// Show the all-time high and the distance from it.
+func athCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	tgt := strings.ToLower(list.target)
+	ath := coin.MarketData.Ath[tgt]
+	if ath == 0 {
+		return "ATH:n/a", paintInfo
+	}
+	return "ATH:" + list.numbers.price(ath, list.target) + " (" +
+		list.numbers.percent(coin.MarketData.AthChangePc[tgt], false) + ")", paintInfo
+}
+
+// Show the circulating, total and max supply.
+func supplyCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	md := coin.MarketData
+	return "SUP:" + supplyAmount(md.CirculatingSupply, list) + "/" + supplyAmount(md.TotalSupply, list) + "/" +
+		supplyAmount(md.MaxSupply, list), paintInfo
+}
+
+func change1hCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	return changeCell("1h:", coin.MarketData.PriceChange1hPc, list)
+}
+
+func change7dCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	return changeCell("7d:", coin.MarketData.PriceChange7dPc, list)
+}
+
+func change30dCell(coin cgapi.CGCoinSingleton, list listing) (string, paint) {
+	return changeCell("30d:", coin.MarketData.PriceChange30dPc, list)
+}
+
+// Returns a labeled amount in the target currency in compact notation.
+func amountCell(label string, list listing, amounts map[string]float64) string {
+	v := amounts[strings.ToLower(list.target)]
+	if v == 0 {
+		return label + "n/a"
+	}
+	return label + list.numbers.amount(v, list.target)
+}
+
+// Returns a supply in compact notation, or - when it is unknown or unlimited.
+func supplyAmount(v float64, list listing) string {
+	if v == 0 {
+		return "-"
+	}
+	return list.numbers.compact(v)
+}
+
+// Returns a price change in the target currency, colored by direction.
+func changeCell(label string, changes map[string]float64, list listing) (string, paint) {
+	pc, ok := changes[strings.ToLower(list.target)]
+	if !ok {
+		return label + "n/a", paintInfo
+	}
+	return label + list.numbers.percent(pc, true), movePaint(pc)
+}
@@ -0,0 +1,136 @@
+// markets.go
+// The markets command lists every exchange ticker for a single coin.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ccpc/cgapi"
+)
+
+// Field widths for a market listing.
+const (
+	marketExchangeWidth = 24
+	marketPairWidth     = 16
+	marketPriceWidth    = 20
+	marketVolumeWidth   = 22
+	marketSpreadWidth   = 14
+	marketTrustWidth    = 8
+)
+
+// marketFilter defines which exchange tickers are shown and in which order.
+type marketFilter struct {
+	target   string
+	minTrust string
+	sortBy   string
+}
+
+// Lists the exchange tickers for a coin symbol.
+func runMarkets(ctx context.Context, args []string, filter marketFilter, list listing) {
+	if len(args) != 1 {
+		usrMessage("The markets command takes exactly one coin symbol.", true, list)
+	}
+	if filter.sortBy != "volume" && filter.sortBy != "spread" {
+		usrMessage("Markets can only be sorted by volume or spread.", true, list)
+	}
+	symb, id := lookupCoin(args[0], list)
+	if id == "" {
+		usrMessage(unknownSymbolMessage(symb), true, list)
+	}
+	coin, err := fetchTickers(ctx, id)
+	if err != nil {
+		requestFailed(ctx, list)
+	}
+	tickers := filterTickers(coin.Tickers, filter)
+	sortTickers(tickers, filter.sortBy)
+
+	tPrint(symb, list.symbol, list, paintAccent, list.symbolWidth)
+	tPrint(coin.Name, list.name, list, paintLabel, list.nameWidth)
+	tPrint(fmt.Sprintf("%d of %d markets", len(tickers), len(coin.Tickers)), true, list,
+		paintInfo, list.priceWidth)
+	fmt.Println(" ")
+	for _, t := range tickers {
+		generateMarketTicker(t, list)
+	}
+}
+
+// Fetches every exchange ticker of a coin, following the pages until one
+// comes back short.
+func fetchTickers(ctx context.Context, id string) (cgapi.CGCoinTickers, error) {
+	var coin cgapi.CGCoinTickers
+	for page := 1; ; page++ {
+		res, err := httpRequest(ctx, cgapi.CGCoinURL+id+cgapi.CGTickersPath+"?page="+strconv.Itoa(page), userAgent)
+		if err != nil {
+			return coin, err
+		}
+		var pageCoin cgapi.CGCoinTickers
+		if err := json.Unmarshal(res, &pageCoin); err != nil {
+			return coin, err
+		}
+		coin.Name = pageCoin.Name
+		coin.Tickers = append(coin.Tickers, pageCoin.Tickers...)
+		if len(pageCoin.Tickers) < cgapi.CGTickersPerPage {
+			return coin, nil
+		}
+	}
+}
+
+// Returns the tickers which pass the target and trust score filters.
+func filterTickers(tickers []cgapi.CGTicker, filter marketFilter) []cgapi.CGTicker {
+	var out []cgapi.CGTicker
+	for _, t := range tickers {
+		if filter.target != "" && !strings.EqualFold(t.Target, filter.target) {
+			continue
+		}
+		if cgapi.TrustScoreRanks[t.TrustScore] < cgapi.TrustScoreRanks[filter.minTrust] {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// Sorts tickers by descending volume or ascending bid/ask spread.
+// Tickers without a known spread are sorted last.
+func sortTickers(tickers []cgapi.CGTicker, by string) {
+	sort.SliceStable(tickers, func(i, j int) bool {
+		if by == "spread" {
+			a, b := tickers[i].BidAskSpreadPc, tickers[j].BidAskSpreadPc
+			if a <= 0 || b <= 0 {
+				return a > 0
+			}
+			return a < b
+		}
+		return tickers[i].Volume > tickers[j].Volume
+	})
+}
+
+// Generate a ticker for a single exchange market.
+func generateMarketTicker(t cgapi.CGTicker, list listing) {
+	tPrint(t.Market.Name, true, list, paintLabel, marketExchangeWidth)
+	tPrint(t.Base+"/"+t.Target, true, list, paintAccent, marketPairWidth)
+	tPrint(list.numbers.price(t.Last, strings.ToUpper(t.Target)), true, list, paintInfo, marketPriceWidth)
+	tPrint("VOL:"+list.numbers.compact(t.Volume), true, list, paintInfo, marketVolumeWidth)
+	if t.BidAskSpreadPc > 0 {
+		tPrint("SPR:"+list.numbers.number(t.BidAskSpreadPc, 3)+"%", true, list, paintInfo, marketSpreadWidth)
+	} else {
+		tPrint("no spread", true, list, paintInfo, marketSpreadWidth)
+	}
+	switch t.TrustScore {
+	case "green":
+		tPrint(t.TrustScore, true, list, paintUp, marketTrustWidth)
+	case "yellow":
+		tPrint(t.TrustScore, true, list, paintWarn, marketTrustWidth)
+	case "red":
+		tPrint(t.TrustScore, true, list, paintDown, marketTrustWidth)
+	default:
+		tPrint("n/a", true, list, paintInfo, marketTrustWidth)
+	}
+	fmt.Println(" ")
+}
@@ -0,0 +1,74 @@
+// metrics.go
+// Prometheus metrics for the serve command, in the text exposition format.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// apiCounters counts the requests made by httpRequest.
+var apiCounters struct {
+	requests    atomic.Uint64
+	errors      atomic.Uint64
+	rateLimited atomic.Uint64
+}
+
+// priceGauges maps gauge names to their help text and the suffix of their
+// key in the simple price data, after the target currency.
+var priceGauges = []struct {
+	name   string
+	help   string
+	suffix string
+}{
+	{"ccpc_price", "Current price of a coin in the target currency.", ""},
+	{"ccpc_price_change_24h_percent", "Price change of a coin over 24 hours, in percent.", "_24h_change"},
+	{"ccpc_volume_24h", "Trading volume of a coin over 24 hours in the target currency.", "_24h_vol"},
+	{"ccpc_market_cap", "Market capitalization of a coin in the target currency.", "_market_cap"},
+}
+
+// Replaces the characters which must be escaped in a label value.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Writes the price gauges and API counters.
+func (ps *priceStore) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	for _, g := range priceGauges {
+		writeMetricHeader(w, g.name, g.help, "gauge")
+		for _, id := range ps.ids {
+			data, ok := ps.prices[id]
+			if !ok {
+				continue
+			}
+			for _, tgt := range ps.targets {
+				val, ok := data[tgt+g.suffix]
+				if !ok {
+					continue
+				}
+				fmt.Fprintf(w, "%s{coin=\"%s\",symbol=\"%s\",target=\"%s\"} %g\n", g.name,
+					labelEscaper.Replace(id), labelEscaper.Replace(ps.symbols[id]), labelEscaper.Replace(tgt), val)
+			}
+		}
+	}
+	if !ps.updated.IsZero() {
+		writeMetricHeader(w, "ccpc_last_update_timestamp_seconds", "Time of the last successful price update.", "gauge")
+		fmt.Fprintf(w, "ccpc_last_update_timestamp_seconds %d\n", ps.updated.Unix())
+	}
+	writeMetricHeader(w, "ccpc_api_requests_total", "Requests made to the Coin Gecko API.", "counter")
+	fmt.Fprintf(w, "ccpc_api_requests_total %d\n", apiCounters.requests.Load())
+	writeMetricHeader(w, "ccpc_api_errors_total", "Requests to the Coin Gecko API which failed.", "counter")
+	fmt.Fprintf(w, "ccpc_api_errors_total %d\n", apiCounters.errors.Load())
+	writeMetricHeader(w, "ccpc_api_rate_limited_total", "Requests to the Coin Gecko API which were rate limited.", "counter")
+	fmt.Fprintf(w, "ccpc_api_rate_limited_total %d\n", apiCounters.rateLimited.Load())
+}
+
+// Writes the HELP and TYPE lines for a metric.
+func writeMetricHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
@@ -0,0 +1,123 @@
+// progress.go
+// Progress is shown on stderr while a request is in flight, as a spinner
+// with an N/M count for multi-coin fetches, and only when stderr is a
+// terminal, so that piped and redirected output stays clean.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// verbosity is how much ccpc reports on stderr.
+type verbosity int
+
+const (
+	quietLevel verbosity = iota
+	normalLevel
+	verboseLevel
+)
+
+// outputLevel is set with --quiet and --verbose.
+var outputLevel = normalLevel
+
+// showProgress is whether requests show a progress line.
+var showProgress = true
+
+const spinnerInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"|", "/", "-", `\`}
+
+// progress is the state of the progress line. Only one spinner is drawn at
+// a time; requests made while it is drawn do not draw another.
+var progress struct {
+	sync.Mutex
+	label    string
+	done     int
+	total    int
+	spinning bool
+}
+
+// Decides whether progress is shown, once the flags are parsed.
+func initProgress(enabled bool) {
+	showProgress = enabled && outputLevel > quietLevel && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// Starts counting the requests of a fetch of total coins, which the
+// progress line shows as N/M.
+func startCount(label string, total int) {
+	progress.Lock()
+	progress.label, progress.done, progress.total = label, 0, total
+	progress.Unlock()
+}
+
+// Counts a finished request of the current fetch.
+func countStep() {
+	progress.Lock()
+	progress.done++
+	progress.Unlock()
+}
+
+// Ends the current count.
+func endCount() {
+	startCount("", 0)
+}
+
+// Draws a spinner on stderr until the returned function is called, which
+// clears it.
+func spin() (stop func()) {
+	progress.Lock()
+	if !showProgress || progress.spinning {
+		progress.Unlock()
+		return func() {}
+	}
+	progress.spinning = true
+	progress.Unlock()
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tick := time.NewTicker(spinnerInterval)
+		defer tick.Stop()
+		for frame := 0; ; frame++ {
+			drawProgress(spinnerFrames[frame%len(spinnerFrames)])
+			select {
+			case <-quit:
+				return
+			case <-tick.C:
+			}
+		}
+	}()
+	return func() {
+		close(quit)
+		wg.Wait()
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		progress.Lock()
+		progress.spinning = false
+		progress.Unlock()
+	}
+}
+
+// Draws the progress line with a spinner frame.
+func drawProgress(frame string) {
+	progress.Lock()
+	line := "Fetching data..."
+	if progress.total > 0 {
+		line = fmt.Sprintf("%s %d/%d", progress.label, progress.done+1, progress.total)
+	}
+	progress.Unlock()
+	fmt.Fprint(os.Stderr, "\r\033[K"+frame+" "+line)
+}
+
+// Prints a diagnostic on stderr if --verbose is set.
+func verboseMessage(format string, args ...interface{}) {
+	if outputLevel >= verboseLevel {
+		fmt.Fprintf(os.Stderr, "ccpc: "+format+"\n", args...)
+	}
+}
@@ -0,0 +1,233 @@
+// rankings.go
+// The top, trending, gainers and losers commands list coins from market data
+// instead of fetching each coin separately.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ccpc/cgapi"
+)
+
+const (
+	defaultRankingCount = 10
+	// moversUniverse is how many coins by market cap are considered for gainers and losers.
+	moversUniverse = cgapi.CGMarketsPerPage
+)
+
+// Lists the top N coins by market cap.
+func runTop(ctx context.Context, args []string, list listing, fltr listingFilter) {
+	n := rankingCount(args, list)
+	markets, err := fetchMarkets(ctx, n, nil, list.target)
+	if err != nil {
+		requestFailed(ctx, list)
+	}
+	renderMarkets(ctx, markets, list, fltr)
+}
+
+// Lists the trending coins, most searched first.
+func runTrending(ctx context.Context, list listing, fltr listingFilter) {
+	res, err := httpRequest(ctx, cgapi.CGTrendingURL, userAgent)
+	if err != nil {
+		requestFailed(ctx, list)
+	}
+	var trending cgapi.CGTrending
+	json.Unmarshal(res, &trending)
+	var ids []string
+	for _, c := range trending.Coins {
+		ids = append(ids, c.Item.ID)
+	}
+	if len(ids) == 0 {
+		usrMessage("No trending coins were returned.", true, list)
+	}
+	markets, err := fetchMarkets(ctx, len(ids), ids, list.target)
+	if err != nil {
+		requestFailed(ctx, list)
+	}
+	// Market data comes back by market cap, so restore the trending order.
+	order := make(map[string]int)
+	for i, id := range ids {
+		order[id] = i
+	}
+	found := make(map[string]bool)
+	for _, m := range markets {
+		found[m.ID] = true
+	}
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		usrMessage("No market data for trending coins: "+strings.Join(missing, ", ")+"; leaving them out.", false, list)
+	}
+	sort.SliceStable(markets, func(i, j int) bool {
+		return order[markets[i].ID] < order[markets[j].ID]
+	})
+	renderMarkets(ctx, markets, list, fltr)
+}
+
+// Lists the N coins with the biggest 24h gains or losses among the
+// top coins by market cap.
+func runMovers(ctx context.Context, args []string, gainers bool, list listing, fltr listingFilter) {
+	n := rankingCount(args, list)
+	if n > moversUniverse {
+		usrMessage(fmt.Sprintf("Gainers and losers are among the top %d coins by market cap, so at most %d are listed.", moversUniverse, moversUniverse), false, list)
+	}
+	markets, err := fetchMarkets(ctx, moversUniverse, nil, list.target)
+	if err != nil {
+		requestFailed(ctx, list)
+	}
+	sort.SliceStable(markets, func(i, j int) bool {
+		if gainers {
+			return markets[i].PriceChange24hPc > markets[j].PriceChange24hPc
+		}
+		return markets[i].PriceChange24hPc < markets[j].PriceChange24hPc
+	})
+	if len(markets) > n {
+		markets = markets[:n]
+	}
+	renderMarkets(ctx, markets, list, fltr)
+}
+
+// Returns the count given as the first argument, or the default.
+func rankingCount(args []string, list listing) int {
+	if len(args) == 0 {
+		return defaultRankingCount
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		usrMessage("Expected a number of coins, not '"+args[0]+"'.", true, list)
+	}
+	return n
+}
+
+// Fetches market data for up to n coins by market cap, optionally only
+// for the given coin IDs, requesting as many pages as needed. Pages are
+// only as large as n needs.
+func fetchMarkets(ctx context.Context, n int, ids []string, target string) ([]cgapi.CGCoinMarket, error) {
+	perPage := min(n, cgapi.CGMarketsPerPage)
+	var markets []cgapi.CGCoinMarket
+	for page := 1; len(markets) < n; page++ {
+		URL := cgapi.CGMarketsURL + "?vs_currency=" + strings.ToLower(target) +
+			"&order=market_cap_desc&per_page=" + strconv.Itoa(perPage) +
+			"&page=" + strconv.Itoa(page) + "&price_change_percentage=1h,7d,30d"
+		if len(ids) > 0 {
+			URL += "&ids=" + strings.Join(ids, ",")
+		}
+		res, err := httpRequest(ctx, URL, userAgent)
+		if err != nil {
+			return nil, err
+		}
+		var pageMarkets []cgapi.CGCoinMarket
+		if err := json.Unmarshal(res, &pageMarkets); err != nil {
+			return nil, err
+		}
+		markets = append(markets, pageMarkets...)
+		if len(pageMarkets) < perPage {
+			break
+		}
+	}
+	if len(markets) > n {
+		markets = markets[:n]
+	}
+	return markets, nil
+}
+
+// Renders market data through the normal listing, after filtering.
+func renderMarkets(ctx context.Context, markets []cgapi.CGCoinMarket, list listing, fltr listingFilter) {
+	global := printGlobalHeader(ctx, list)
+	var coins []cgapi.CGCoinSingleton
+	for _, m := range markets {
+		coins = append(coins, marketToCoin(m, list.target))
+	}
+	if err := addTargetPrices(ctx, coins, list); err != nil {
+		usrMessage("Could not load prices in the other targets.", false, list)
+	}
+	renderCoins(filterCoins(coins, list, fltr), list, global)
+	if len(coins) == 0 {
+		usrMessage(fmt.Sprintf("No coins to list in %s.", list.target), false, list)
+	}
+}
+
+// Adds the prices and 24h changes in the listing's other targets to coins,
+// replacing any in their market data. Coins converted from market data
+// have only the first target. The prices for every coin and target are
+// fetched in one request.
+func addTargetPrices(ctx context.Context, coins []cgapi.CGCoinSingleton, list listing) error {
+	if len(list.targets) < 2 || len(coins) == 0 {
+		return nil
+	}
+	var ids, targets []string
+	for _, c := range coins {
+		ids = append(ids, c.ID)
+	}
+	for _, tgt := range list.targets[1:] {
+		targets = append(targets, strings.ToLower(tgt))
+	}
+	prices, err := fetchSimplePrices(ctx, ids, targets)
+	if err != nil {
+		return err
+	}
+	for i := range coins {
+		data, ok := prices[coins[i].ID]
+		if !ok {
+			continue
+		}
+		md := &coins[i].MarketData
+		if md.CurrentPrice == nil {
+			md.CurrentPrice = make(map[string]float64)
+		}
+		if md.PriceChange24hCurPc == nil {
+			md.PriceChange24hCurPc = make(map[string]float64)
+		}
+		for _, tgt := range targets {
+			if p, ok := data[tgt]; ok {
+				md.CurrentPrice[tgt] = p
+			}
+			if pc, ok := data[tgt+"_24h_change"]; ok {
+				md.PriceChange24hCurPc[tgt] = pc
+			}
+		}
+	}
+	return nil
+}
+
+// Converts market data to a coin without exchange tickers, so that its
+// price is taken from the market data.
+func marketToCoin(m cgapi.CGCoinMarket, target string) cgapi.CGCoinSingleton {
+	tgt := strings.ToLower(target)
+	coin := cgapi.CGCoinSingleton{
+		ID:            m.ID,
+		Symbol:        m.Symbol,
+		Name:          m.Name,
+		MarketCapRank: m.MarketCapRank,
+		LastUpdated:   m.LastUpdated,
+	}
+	md := &coin.MarketData
+	md.CurrentPrice = map[string]float64{tgt: m.CurrentPrice}
+	md.MarketCap = map[string]float64{tgt: m.MarketCap}
+	md.FullyDilutedValuation = map[string]float64{tgt: m.FullyDilutedValuation}
+	md.TotalVolume = map[string]float64{tgt: m.TotalVolume}
+	md.High24h = map[string]float64{tgt: m.High24h}
+	md.Low24h = map[string]float64{tgt: m.Low24h}
+	md.Ath = map[string]float64{tgt: m.Ath}
+	md.AthChangePc = map[string]float64{tgt: m.AthChangePc}
+	md.PriceChange24h = m.PriceChange24h
+	md.PriceChange24hPc = m.PriceChange24hPc
+	md.PriceChange24hCurPc = map[string]float64{tgt: m.PriceChange24hPc}
+	md.PriceChange1hPc = map[string]float64{tgt: m.PriceChange1hPc}
+	md.PriceChange7dPc = map[string]float64{tgt: m.PriceChange7dPc}
+	md.PriceChange30dPc = map[string]float64{tgt: m.PriceChange30dPc}
+	md.CirculatingSupply = m.CirculatingSupply
+	md.TotalSupply = m.TotalSupply
+	md.MaxSupply = m.MaxSupply
+	return coin
+}
@@ -0,0 +1,293 @@
+// search.go
+// The search command finds coin symbols by fuzzy matching.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ccpc/cgapi"
+)
+
+const (
+	defaultSearchResults = 20
+	searchRankWidth      = 8
+)
+
+// Scores given to a match, best first.
+const (
+	matchExact       = 100
+	matchPrefix      = 80
+	matchSubstring   = 60
+	matchSubsequence = 40
+	// matchRemote is the least score of a coin returned by the API search,
+	// which may have matched on something other than symbol, ID or name.
+	matchRemote = 30
+)
+
+// searchResult defines a coin found by the search command.
+type searchResult struct {
+	symbol string
+	id     string
+	name   string
+	rank   int
+	score  int
+}
+
+// Searches the known coins for a query and lists the best matches.
+// With remote set, the API is searched too, which adds names and ranks.
+func runSearch(ctx context.Context, args []string, remote bool, list listing, fltr listingFilter) {
+	if len(args) == 0 {
+		usrMessage("The search command needs a query.", true, list)
+	}
+	query := strings.ToLower(strings.Join(args, " "))
+	results := make(map[string]*searchResult)
+	for symb, id := range cgapi.CGCoinURLs {
+		name := coinName(id)
+		score := matchScore(query, symb, id, name)
+		if r, ok := results[id]; score > 0 && (!ok || score > r.score) {
+			results[id] = &searchResult{symbol: symb, id: id, name: name, rank: coinRank(id), score: score}
+		}
+	}
+	if remote {
+		symbols := coinSymbolsByID()
+		res, err := httpRequest(ctx, cgapi.CGSearchURL+"?query="+url.QueryEscape(query), userAgent)
+		if err != nil {
+			requestFailed(ctx, list)
+		}
+		var search cgapi.CGSearch
+		json.Unmarshal(res, &search)
+		for _, c := range search.Coins {
+			symb, ok := symbols[c.ID]
+			if !ok {
+				// Coins missing from CGCoinURLs cannot be listed by symbol.
+				continue
+			}
+			score := matchScore(query, symb, c.ID, c.Name)
+			if score < matchRemote {
+				score = matchRemote
+			}
+			r, ok := results[c.ID]
+			if !ok {
+				r = &searchResult{symbol: symb, id: c.ID}
+				results[c.ID] = r
+			}
+			if score > r.score {
+				r.score = score
+			}
+			r.name = c.Name
+			r.rank = c.MarketCapRank
+		}
+	}
+
+	var sorted []*searchResult
+	for _, r := range results {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if (a.rank > 0) != (b.rank > 0) {
+			return a.rank > 0
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.symbol < b.symbol
+	})
+	n := defaultSearchResults
+	if fltr.top > 0 {
+		n = fltr.top
+	}
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	if len(sorted) == 0 {
+		usrMessage("No coins match '"+query+"'.", false, list)
+	}
+	for _, r := range sorted {
+		generateSearchTicker(*r, list)
+	}
+}
+
+// Returns the best score of a query against any of the candidate strings,
+// or zero if none match.
+func matchScore(query string, candidates ...string) int {
+	best := 0
+	for _, c := range candidates {
+		c = strings.ToLower(c)
+		score := 0
+		switch {
+		case c == "":
+		case c == query:
+			score = matchExact
+		case strings.HasPrefix(c, query):
+			score = matchPrefix
+		case strings.Contains(c, query):
+			score = matchSubstring
+		case isSubsequence(query, c):
+			score = matchSubsequence
+		}
+		if score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// Reports whether every rune of sub appears in str, in order.
+func isSubsequence(sub, str string) bool {
+	rs := []rune(sub)
+	if len(rs) == 0 {
+		return false
+	}
+	i := 0
+	for _, r := range str {
+		if r == rs[i] {
+			i++
+			if i == len(rs) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Generate a ticker for a search result.
+func generateSearchTicker(r searchResult, list listing) {
+	tPrint(r.symbol, true, list, paintAccent, list.symbolWidth)
+	if r.name != "" {
+		tPrint(r.name, list.name, list, paintLabel, list.nameWidth)
+	} else {
+		tPrint("-", list.name, list, paintLabel, list.nameWidth)
+	}
+	tPrint(r.id, true, list, paintInfo, list.nameWidth)
+	if r.rank > 0 {
+		tPrint("#"+strconv.Itoa(r.rank), true, list, paintUp, searchRankWidth)
+	} else {
+		tPrint("unranked", true, list, paintInfo, searchRankWidth)
+	}
+	fmt.Println(" ")
+}
+
+// Looks up a symbol like coinID. If it is unknown and the listing resolves
+// names, it is also looked up as a coin ID or name, e.g. "Bitcoin Cash" or
+// "binance coin".
+func lookupCoin(arg string, list listing) (symb, id string) {
+	symb, id = coinID(arg)
+	if id != "" || !list.resolveNames {
+		return symb, id
+	}
+	if nid, ok := coinIDsByName()[normalName(arg)]; ok {
+		return coinSymbolsByID()[nid], nid
+	}
+	return symb, id
+}
+
+// Returns a coin name or ID in lower case without spaces or punctuation,
+// so that "Binance Coin", "binance-coin" and "binancecoin" are the same.
+func normalName(str string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(str) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Returns a message for an unknown symbol, suggesting known coins whose
+// symbol, ID or name is close to it.
+func unknownSymbolMessage(symb string) string {
+	msg := "Unknown coin symbol '" + symb + "'"
+	suggestions := suggestCoins(symb, maxSuggestions)
+	if len(suggestions) > 0 {
+		msg += "; did you mean " + strings.Join(suggestions, ", ") + "?"
+	}
+	return msg
+}
+
+// maxSuggestions is how many coins are suggested for an unknown symbol.
+const maxSuggestions = 3
+
+// Returns up to n known coins, as "symbol (id)", whose symbol, ID or name
+// is within a small edit distance of arg, closest and then largest first.
+// A match must keep at least one rune of arg, so short symbols do not
+// suggest every other short symbol.
+func suggestCoins(arg string, n int) []string {
+	arg = strings.ToLower(arg)
+	runes := utf8.RuneCountInString(arg)
+	// Allow one edit for every three runes, and at least one.
+	maxDist := runes/3 + 1
+	type suggestion struct {
+		symb string
+		id   string
+		dist int
+		rank int
+	}
+	var found []suggestion
+	for symb, id := range cgapi.CGCoinURLs {
+		dist := editDistance(arg, strings.ToLower(symb))
+		if d := editDistance(arg, id); d < dist {
+			dist = d
+		}
+		if name := strings.ToLower(coinName(id)); name != "" {
+			if d := editDistance(arg, name); d < dist {
+				dist = d
+			}
+		}
+		if dist <= maxDist && dist < runes {
+			found = append(found, suggestion{symb, id, dist, coinRank(id)})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.dist != b.dist {
+			return a.dist < b.dist
+		}
+		if (a.rank > 0) != (b.rank > 0) {
+			return a.rank > 0
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.symb < b.symb
+	})
+	var out []string
+	for i := 0; i < len(found) && i < n; i++ {
+		out = append(out, found[i].symb+" ("+found[i].id+")")
+	}
+	return out
+}
+
+// Returns the Levenshtein distance between two strings, counted in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,414 @@
+// serve.go
+// The serve command fronts the Coin Gecko API for other tools, serving
+// cached prices as JSON and as Prometheus metrics.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ccpc/cgapi"
+)
+
+// priceStore caches prices for the serve command. Missing or expired prices
+// are fetched in batches, no more often than the limiter allows.
+type priceStore struct {
+	fetchMu sync.Mutex // serializes fetches from the API
+	limiter *rateLimiter
+	ttl     time.Duration
+	list    listing // looks up requested symbols, e.g. with --resolve-names
+
+	mu      sync.RWMutex // guards everything below
+	prices  map[string]map[string]float64
+	fetched map[string]time.Time
+	updated time.Time
+
+	global        cgapi.CGGlobalData
+	globalFetched time.Time
+
+	// Coins and targets which are refreshed on an interval for /metrics.
+	ids     []string
+	symbols map[string]string
+	targets []string
+}
+
+// rateLimiter spaces out requests to stay under the API rate limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// apiPrice is a coin price in a target as served by /v1/price.
+type apiPrice struct {
+	Symbol      string  `json:"symbol"`
+	ID          string  `json:"id"`
+	Target      string  `json:"target"`
+	Price       float64 `json:"price"`
+	Change24hPc float64 `json:"change_24h"`
+	Volume24h   float64 `json:"volume_24h"`
+	MarketCap   float64 `json:"market_cap"`
+}
+
+// apiPriceResponse is the response body of /v1/price. Prices are listed by
+// coin, then by target.
+type apiPriceResponse struct {
+	Targets []string   `json:"targets"`
+	Prices  []apiPrice `json:"prices"`
+	Unknown []string   `json:"unknown,omitempty"`
+}
+
+// apiCoin is a known coin as served by /v1/coins.
+type apiCoin struct {
+	Symbol string `json:"symbol"`
+	ID     string `json:"id"`
+}
+
+// serveShutdownTimeout is how long requests in flight are given to finish
+// when the serve command is interrupted.
+const serveShutdownTimeout = 5 * time.Second
+
+// Runs the serve command until ctx is canceled, then shuts the servers down.
+func runServe(ctx context.Context, args []string, list listing, listenAddr, metricsAddr string, dur, perMinute uint) {
+	if dur == 0 {
+		usrMessage("The serve command needs an update duration of at least one second.", true, list)
+	}
+	store := &priceStore{
+		limiter: &rateLimiter{},
+		ttl:     time.Duration(dur) * time.Second,
+		list:    list,
+		prices:  make(map[string]map[string]float64),
+		fetched: make(map[string]time.Time),
+		symbols: make(map[string]string),
+	}
+	for _, tgt := range list.targets {
+		store.targets = append(store.targets, strings.ToLower(tgt))
+	}
+	if len(store.targets) == 0 {
+		store.targets = []string{strings.ToLower(list.target)}
+	}
+	if perMinute > 0 {
+		store.limiter.interval = time.Minute / time.Duration(perMinute)
+	}
+	for _, arg := range args {
+		symb, id := lookupCoin(arg, list)
+		if id == "" {
+			usrMessage(unknownSymbolMessage(symb), false, list)
+		} else if _, ok := store.symbols[id]; !ok {
+			store.symbols[id] = strings.ToLower(symb)
+			store.ids = append(store.ids, id)
+		}
+	}
+	sort.Strings(store.ids)
+
+	if len(store.ids) > 0 {
+		go func() {
+			ticker := time.NewTicker(store.ttl)
+			defer ticker.Stop()
+			for {
+				if _, err := store.get(store.ids, store.targets); err != nil {
+					usrMessage("Could not refresh prices: "+err.Error(), false, list)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/price", store.servePrice)
+	mux.HandleFunc("/v1/coins", serveCoins)
+	mux.HandleFunc("/v1/global", store.serveGlobal)
+	mux.HandleFunc("/metrics", store.serveMetrics)
+	// Both servers report failures here, so that ccpc exits from this goroutine.
+	failed := make(chan string, 2)
+	srv := &http.Server{Addr: listenAddr, Handler: mux}
+	servers := []*http.Server{srv}
+	if metricsAddr != "" && metricsAddr != listenAddr {
+		metricsMux := http.NewServeMux()
+		metricsMux.HandleFunc("/metrics", store.serveMetrics)
+		metricsSrv := &http.Server{Addr: metricsAddr, Handler: metricsMux}
+		servers = append(servers, metricsSrv)
+		go func() {
+			failed <- "Could not serve metrics: " + metricsSrv.ListenAndServe().Error()
+		}()
+		usrMessage("Serving metrics on "+metricsAddr+"/metrics.", false, list)
+	}
+	go func() {
+		failed <- "Could not serve prices: " + srv.ListenAndServe().Error()
+	}()
+	usrMessage("Serving prices on "+listenAddr+", caching for "+fmt.Sprint(dur)+" seconds.", false, list)
+	select {
+	case msg := <-failed:
+		usrMessage(msg, true, list)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			usrMessage("Could not shut down the server on "+s.Addr+": "+err.Error(), false, list)
+		}
+	}
+}
+
+// Serves /v1/price?symbols=btc,eth&target=jpy,usd.
+func (ps *priceStore) servePrice(w http.ResponseWriter, r *http.Request) {
+	var targets []string
+	for _, tgt := range strings.Split(strings.ToLower(r.URL.Query().Get("target")), ",") {
+		if tgt == "" || containsString(targets, tgt) {
+			continue
+		}
+		if !knownCurrency(tgt) {
+			writeJSONError(w, http.StatusBadRequest, "unknown target currency: "+tgt)
+			return
+		}
+		targets = append(targets, tgt)
+	}
+	if len(targets) == 0 {
+		targets = ps.targets
+	}
+	body := apiPriceResponse{Targets: targets, Prices: []apiPrice{}}
+	var ids []string
+	symbols := make(map[string]string)
+	for _, arg := range strings.Split(r.URL.Query().Get("symbols"), ",") {
+		if arg == "" {
+			continue
+		}
+		symb, id := lookupCoin(arg, ps.list)
+		if id == "" {
+			body.Unknown = append(body.Unknown, arg)
+		} else if _, ok := symbols[id]; !ok {
+			symbols[id] = strings.ToLower(symb)
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 && len(body.Unknown) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "no symbols given")
+		return
+	}
+	prices, err := ps.get(ids, targets)
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	for _, id := range ids {
+		data, ok := prices[id]
+		if !ok {
+			body.Unknown = append(body.Unknown, symbols[id])
+			continue
+		}
+		for _, tgt := range targets {
+			body.Prices = append(body.Prices, apiPrice{
+				Symbol:      symbols[id],
+				ID:          id,
+				Target:      tgt,
+				Price:       data[tgt],
+				Change24hPc: data[tgt+"_24h_change"],
+				Volume24h:   data[tgt+"_24h_vol"],
+				MarketCap:   data[tgt+"_market_cap"],
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// Serves /v1/coins, the list of known coin symbols and IDs.
+func serveCoins(w http.ResponseWriter, r *http.Request) {
+	coins := []apiCoin{}
+	for _, symb := range mapToSortedStrings(cgapi.CGCoinURLs) {
+		coins = append(coins, apiCoin{Symbol: symb, ID: cgapi.CGCoinURLs[symb]})
+	}
+	writeJSON(w, http.StatusOK, coins)
+}
+
+// Serves /v1/global, the global market data for all currencies.
+func (ps *priceStore) serveGlobal(w http.ResponseWriter, r *http.Request) {
+	global, err := ps.getGlobal()
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, global)
+}
+
+// Returns the global market data, fetching it if it is older than the
+// store's ttl. If the fetch fails, expired data is returned if there is any.
+func (ps *priceStore) getGlobal() (cgapi.CGGlobalData, error) {
+	ps.fetchMu.Lock()
+	defer ps.fetchMu.Unlock()
+	ps.mu.RLock()
+	global, fetched := ps.global, ps.globalFetched
+	ps.mu.RUnlock()
+	logCache("global", time.Since(fetched) <= ps.ttl)
+	if time.Since(fetched) <= ps.ttl {
+		return global, nil
+	}
+	ps.limiter.wait()
+	fresh, err := fetchGlobal(context.Background())
+	if err != nil {
+		if fetched.IsZero() {
+			return global, err
+		}
+		return global, nil
+	}
+	ps.mu.Lock()
+	ps.global, ps.globalFetched = fresh, time.Now()
+	ps.mu.Unlock()
+	return fresh, nil
+}
+
+// Returns price data for the coin IDs and targets, fetching any which are
+// missing or older than the store's ttl in a single request. If the fetch
+// fails, expired prices are returned as long as every coin has some.
+func (ps *priceStore) get(ids, targets []string) (cgapi.CGSimplePrice, error) {
+	expired := ps.expired(ids, targets)
+	logCache("prices", len(expired) == 0)
+	if len(expired) > 0 {
+		ps.fetchMu.Lock()
+		defer ps.fetchMu.Unlock()
+		// Another request may have fetched these while we waited.
+		if expired := ps.expired(ids, targets); len(expired) > 0 {
+			ps.limiter.wait()
+			// The fetch fills the store for every client, so it is not
+			// canceled with the request which started it.
+			prices, err := fetchSimplePrices(context.Background(), expired, targets)
+			if err != nil {
+				if !ps.cached(ids, targets) {
+					return nil, err
+				}
+			} else {
+				ps.store(expired, targets, prices)
+			}
+		}
+	}
+	return ps.lookup(ids, targets), nil
+}
+
+// Returns the coin IDs which have no price newer than ttl for any target.
+func (ps *priceStore) expired(ids, targets []string) []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	var out []string
+	for _, id := range ids {
+		for _, tgt := range targets {
+			if time.Since(ps.fetched[id+"/"+tgt]) > ps.ttl {
+				out = append(out, id)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Reports whether every coin ID has been fetched for every target.
+func (ps *priceStore) cached(ids, targets []string) bool {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	for _, id := range ids {
+		for _, tgt := range targets {
+			if ps.fetched[id+"/"+tgt].IsZero() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Stores fetched price data. Coins the API did not return are recorded as
+// fetched too, so they are not requested again until they expire.
+func (ps *priceStore) store(ids, targets []string, prices cgapi.CGSimplePrice) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	now := time.Now()
+	for _, id := range ids {
+		for _, tgt := range targets {
+			ps.fetched[id+"/"+tgt] = now
+		}
+		data, ok := prices[id]
+		if !ok {
+			continue
+		}
+		if ps.prices[id] == nil {
+			ps.prices[id] = make(map[string]float64)
+		}
+		for k, v := range data {
+			ps.prices[id][k] = v
+		}
+	}
+	ps.updated = now
+}
+
+// Returns a copy of the cached price data for the coin IDs and targets.
+func (ps *priceStore) lookup(ids, targets []string) cgapi.CGSimplePrice {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	out := make(cgapi.CGSimplePrice)
+	for _, id := range ids {
+		data, ok := ps.prices[id]
+		if !ok {
+			continue
+		}
+		out[id] = make(map[string]float64)
+		for k, v := range data {
+			for _, tgt := range targets {
+				if k == tgt || strings.HasPrefix(k, tgt+"_") {
+					out[id][k] = v
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Blocks until the next request is allowed.
+func (rl *rateLimiter) wait() {
+	rl.mu.Lock()
+	now := time.Now()
+	start := rl.next
+	if start.Before(now) {
+		start = now
+	}
+	rl.next = start.Add(rl.interval)
+	rl.mu.Unlock()
+	time.Sleep(time.Until(start))
+}
+
+// Fetches prices, market caps, volumes and 24h changes for many coins
+// in a single request.
+func fetchSimplePrices(ctx context.Context, ids, targets []string) (cgapi.CGSimplePrice, error) {
+	URL := cgapi.CGSimplePriceURL + "?ids=" + strings.Join(ids, ",") +
+		"&vs_currencies=" + strings.Join(targets, ",") +
+		"&include_market_cap=true&include_24hr_vol=true&include_24hr_change=true"
+	res, err := httpRequest(ctx, URL, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	var prices cgapi.CGSimplePrice
+	if err := json.Unmarshal(res, &prices); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// Writes a value as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Writes an error message as a JSON response.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,85 @@
+// shutdown.go
+// Every command runs with a context which SIGINT and SIGTERM cancel,
+// stopping requests in flight and shutting down the serve command's
+// servers. ccpc then restores the terminal, closes the log and exits with
+// 128 plus the signal number.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// interrupted is the cause of a run context canceled by a signal.
+type interrupted struct {
+	sig os.Signal
+}
+
+// Returns a description of the signal which canceled the run.
+func (i interrupted) Error() string {
+	return "interrupted by " + i.sig.String()
+}
+
+// Returns a context which is canceled on SIGINT or SIGTERM. Calling stop
+// stops listening for signals. After the first signal, signals are no
+// longer caught, so a second one ends ccpc at once even if it is waiting
+// on something which does not watch the context.
+func signalContext() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case s := <-sig:
+			signal.Stop(sig)
+			cancel(interrupted{s})
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sig)
+		cancel(nil)
+	}
+}
+
+// Exits if the context was canceled by a signal, after restoring the
+// terminal and closing the log.
+func exitIfInterrupted(ctx context.Context) {
+	var i interrupted
+	if !errors.As(context.Cause(ctx), &i) {
+		return
+	}
+	logger.Info("interrupted", slog.String("signal", i.sig.String()))
+	restoreTerminal()
+	closeLog()
+	code := 1
+	if s, ok := i.sig.(syscall.Signal); ok {
+		code = 128 + int(s)
+	}
+	os.Exit(code)
+}
+
+// Reports a failed request and exits, with the code for the signal if the
+// request failed because ctx was canceled by one.
+func requestFailed(ctx context.Context, list listing) {
+	exitIfInterrupted(ctx)
+	usrMessage("HTTP request did not complete successfully.", true, list)
+}
+
+// Clears the progress line and any echoed ^C, and resets colors.
+func restoreTerminal() {
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Print("\033[0m")
+	}
+}
@@ -0,0 +1,246 @@
+// stream.go
+// Stream mode updates tickers live from an exchange WebSocket feed.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"ccpc/bnapi"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/term"
+)
+
+const (
+	streamReadTimeout = 60 * time.Second
+	streamMaxBackoff  = 30 * time.Second
+)
+
+// streamRow holds the latest ticker event for one pair in stream mode.
+type streamRow struct {
+	symbol string
+	target string
+	pair   string
+	tick   bnapi.Ticker
+	seen   bool
+}
+
+// Streams live prices for the coins of entries until ctx is canceled, one
+// row per coin and target. Coins with their own target are streamed in it,
+// and others in every target given with -t.
+func runStream(ctx context.Context, entries []watchEntry, list listing, url string) {
+	var rows []streamRow
+	var streams, warnings []string
+	rowIdx := make(map[string]int)
+	refused := make(map[string]bool)
+	for _, e := range entries {
+		symb, id := lookupCoin(e.symbol, list)
+		if id == "" {
+			warnings = append(warnings, unknownSymbolMessage(symb))
+			continue
+		}
+		targets := list.targets
+		if e.target != "" {
+			targets = []string{e.target}
+		} else if len(targets) == 0 {
+			targets = []string{list.target}
+		}
+		for _, tgt := range targets {
+			quote, ok := streamQuote(tgt)
+			if !ok && !refused[tgt] {
+				refused[tgt] = true
+				warnings = append(warnings, "Binance has no pairs against "+tgt+"; use usd or another quote asset such as eur, btc or eth.")
+			}
+			// Other endpoints may list other pairs, so only Binance's are refused.
+			if !ok && url == bnapi.StreamURL {
+				continue
+			}
+			pair := strings.ToUpper(symb + quote)
+			if _, ok := rowIdx[pair]; ok {
+				continue
+			}
+			rowIdx[pair] = len(rows)
+			rows = append(rows, streamRow{symbol: symb, target: tgt, pair: pair})
+			streams = append(streams, bnapi.TickerStream(symb, quote))
+		}
+	}
+	if len(rows) == 0 {
+		for _, w := range warnings {
+			usrMessage(w, false, list)
+		}
+		usrMessage("No known coin symbols to stream.", true, list)
+	}
+
+	ticks := make(chan bnapi.Ticker)
+	status := make(chan string)
+	go streamTickers(ctx, url+strings.Join(streams, "/"), ticks, status)
+
+	// Without a terminal, the drawing is not redrawn in place. Each state
+	// change and ticker event is printed on a line of its own instead.
+	tty := term.IsTerminal(int(os.Stdout.Fd()))
+	if tty {
+		clearScreen()
+	}
+	usrMessage("You are running ccpc in stream mode. Tickers update as trades arrive.", false, list)
+	state := "connecting"
+	if tty {
+		drawStream(rows, state, list, false)
+	} else {
+		drawStreamState(state, list)
+	}
+	// Warnings go below the first drawing, so clearing the screen does not
+	// wipe them.
+	for _, w := range warnings {
+		usrMessage(w, false, list)
+	}
+	for {
+		select {
+		case t := <-ticks:
+			i, ok := rowIdx[t.Symbol]
+			if !ok {
+				continue
+			}
+			rows[i].tick = t
+			rows[i].seen = true
+			if !tty {
+				generateStreamTicker(rows[i], list)
+				continue
+			}
+		case state = <-status:
+			if !tty {
+				drawStreamState(state, list)
+				continue
+			}
+		case <-ctx.Done():
+			return
+		}
+		drawStream(rows, state, list, true)
+	}
+}
+
+// Returns the Binance quote asset for a target currency, reporting whether
+// Binance has spot pairs against it.
+func streamQuote(target string) (string, bool) {
+	quote := target
+	if q, ok := bnapi.QuoteAssets[quote]; ok {
+		quote = q
+	}
+	return quote, containsString(bnapi.SpotQuotes, quote)
+}
+
+// Draws the connection state and one ticker per row. The cursor position
+// is saved before the first drawing and restored to redraw over it, which
+// leaves any lines printed below the drawing in place.
+func drawStream(rows []streamRow, state string, list listing, redraw bool) {
+	if redraw {
+		fmt.Print("\0338")
+	} else {
+		fmt.Print("\0337")
+	}
+	drawStreamState(state, list)
+	for _, r := range rows {
+		generateStreamTicker(r, list)
+	}
+}
+
+// Prints the connection state on a line.
+func drawStreamState(state string, list listing) {
+	if state == "live" {
+		tPrint("live", true, list, paintUp, 9)
+	} else {
+		tPrint("wait", true, list, paintWarn, 9)
+	}
+	tPrint(state, true, list, paintText, list.nameWidth+list.priceWidth)
+	fmt.Println(" ")
+}
+
+// Generate a ticker from the latest stream event for a row.
+func generateStreamTicker(r streamRow, list listing) {
+	tPrint(r.symbol, list.symbol, list, paintAccent, list.symbolWidth)
+	tPrint(r.pair, list.name, list, paintLabel, list.nameWidth)
+	if !r.seen {
+		tPrint("waiting", true, list, paintWarn, list.priceWidth)
+		fmt.Println(" ")
+		return
+	}
+	price := list.numbers.price(r.tick.Last, r.target) + " (" + list.numbers.percent(r.tick.PriceChangePc, true) + "/24h)"
+	tPrint(price, true, list, movePaint(r.tick.PriceChangePc), list.priceWidth)
+	tm := time.Unix(0, r.tick.EventTime*int64(time.Millisecond))
+	tPrint("UPD:"+tm.Format(time.RFC822), list.lastUpdated, list, paintInfo, list.lastUpdatedWidth)
+	tPrint(r.tick.Volume, list.volume, list, paintInfo, list.volumeWidth, "VOL:")
+	fmt.Println(" ")
+}
+
+// Keeps a connection open to the stream URL, reconnecting with exponential
+// backoff, until ctx is canceled. Ticker events are sent on ticks and
+// connection states on status.
+func streamTickers(ctx context.Context, url string, ticks chan<- bnapi.Ticker, status chan<- string) {
+	backoff := time.Second
+	for {
+		if !sendStatus(ctx, status, "connecting") {
+			return
+		}
+		connected, err := readStream(ctx, url, ticks, status)
+		if connected {
+			backoff = time.Second
+		}
+		if !sendStatus(ctx, status, "disconnected ("+err.Error()+"), retrying in "+backoff.String()) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > streamMaxBackoff {
+			backoff = streamMaxBackoff
+		}
+	}
+}
+
+// Reads ticker events from one connection until it fails or ctx is
+// canceled. Reports whether the connection was established.
+func readStream(ctx context.Context, url string, ticks chan<- bnapi.Ticker, status chan<- string) (bool, error) {
+	header := http.Header{}
+	header.Set("User-Agent", userAgent)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	// Closing the connection ends a read which is waiting for a message.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if !sendStatus(ctx, status, "live") {
+		return true, ctx.Err()
+	}
+	for {
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		var msg bnapi.StreamMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return true, err
+		}
+		select {
+		case ticks <- msg.Data:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
+
+// Sends a connection state, reporting false if ctx was canceled first.
+func sendStatus(ctx context.Context, status chan<- string, state string) bool {
+	select {
+	case status <- state:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
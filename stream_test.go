@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ccpc/bnapi"
+
+	"github.com/gorilla/websocket"
+)
+
+const testTick = `{"stream":"btcusdt@ticker","data":{"e":"24hrTicker","E":1587600000000,` +
+	`"s":"BTCUSDT","p":"100.0","P":"1.5","c":"7000.50","C":1587600000000,"v":"1234.5"}}`
+
+// Starts a mock stream server which sends a ticker event on each
+// connection. The first connection is then dropped; later ones stay open.
+func mockStream(t *testing.T) (url string, conns *atomic.Int32) {
+	conns = new(atomic.Int32)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		n := conns.Add(1)
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(testTick)); err != nil {
+			return
+		}
+		if n == 1 {
+			return
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/stream?streams=btcusdt@ticker", conns
+}
+
+// Waits for a ticker event, failing the test after a timeout.
+func nextTick(t *testing.T, ticks <-chan bnapi.Ticker) bnapi.Ticker {
+	t.Helper()
+	select {
+	case tick := <-ticks:
+		return tick
+	case <-time.After(5 * time.Second):
+		t.Fatal("no ticker event was delivered")
+	}
+	return bnapi.Ticker{}
+}
+
+func TestStreamTickers(t *testing.T) {
+	url, conns := mockStream(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticks := make(chan bnapi.Ticker)
+	status := make(chan string, 100)
+	done := make(chan struct{})
+	go func() {
+		streamTickers(ctx, url, ticks, status)
+		close(done)
+	}()
+
+	tick := nextTick(t, ticks)
+	if tick.Symbol != "BTCUSDT" || tick.Last != 7000.5 || tick.PriceChangePc != 1.5 {
+		t.Errorf("got ticker %+v", tick)
+	}
+	// The first connection is dropped, so this event comes from a new one.
+	nextTick(t, ticks)
+	if n := conns.Load(); n != 2 {
+		t.Errorf("got %d connections, want 2", n)
+	}
+	var reconnected bool
+	for len(status) > 0 {
+		if strings.HasPrefix(<-status, "disconnected") {
+			reconnected = true
+		}
+	}
+	if !reconnected {
+		t.Error("the dropped connection was not reported")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("streamTickers did not return after the context was canceled")
+	}
+}
+
+func TestStreamTickersCanceledWhileWaiting(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	status := make(chan string, 100)
+	done := make(chan struct{})
+	go func() {
+		streamTickers(ctx, url, make(chan bnapi.Ticker), status)
+		close(done)
+	}()
+	// Cancel during the backoff after the failed dial.
+	for s := range status {
+		if strings.HasPrefix(s, "disconnected") {
+			break
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("streamTickers did not return after the context was canceled")
+	}
+}
@@ -0,0 +1,271 @@
+// symbols.go
+// Symbols files list the coins to show: one per line as in a watchlist, or
+// as CSV or JSON with a quantity, a target and alert thresholds for each.
+// The symbols file "-" is read from stdin.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// symbolFields are the columns of a CSV symbols file, in the order used
+// when it has no header.
+var symbolFields = []string{"symbol", "quantity", "target", "alert_above", "alert_below", "note"}
+
+// jsonSymbol is an entry of a JSON symbols file.
+type jsonSymbol struct {
+	Symbol     string  `json:"symbol"`
+	Quantity   float64 `json:"quantity"`
+	Target     string  `json:"target"`
+	AlertAbove float64 `json:"alert_above"`
+	AlertBelow float64 `json:"alert_below"`
+	Note       string  `json:"note"`
+}
+
+// Loads the entries of a symbols file, or of stdin for "-", checking that
+// their coins and targets are known. Coins listed more than once are
+// merged. Errors name the file and line.
+func readSymbolsFile(path string, list listing) ([]watchEntry, error) {
+	name := path
+	var data []byte
+	var err error
+	if path == "-" {
+		name = "stdin"
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []watchEntry
+	switch symbolsFormat(path, data) {
+	case "csv":
+		entries, err = parseCSVSymbols(data)
+	case "json":
+		entries, err = parseJSONSymbols(data)
+	default:
+		var lines []watchLine
+		lines, err = parseWatchLines(bytes.NewReader(data))
+		for _, l := range lines {
+			if l.entry != nil {
+				entries = append(entries, *l.entry)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	for _, e := range entries {
+		if err := e.validate(list); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %v", name, e.line, err)
+		}
+	}
+	merged, err := mergeEntries(entries)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return merged, nil
+}
+
+// Returns the format of a symbols file: csv or json by extension, or else
+// by its first line which is not blank or a comment; text otherwise. A
+// note after a '#' does not count, since text files may have commas there.
+func symbolsFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if strings.HasPrefix(line, "[") {
+			return "json"
+		} else if strings.Contains(line, ",") {
+			return "csv"
+		}
+		break
+	}
+	return "text"
+}
+
+// Parses a CSV symbols file. A first row starting with "symbol" is a header
+// naming the columns; otherwise the columns are in symbolFields order.
+// Blank lines and lines starting with '#' are skipped.
+func parseCSVSymbols(data []byte) ([]watchEntry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	fields := symbolFields
+	var entries []watchEntry
+	for first := true; ; first = false {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(rec[0]), "symbol") {
+			fields = nil
+			for _, f := range rec {
+				f = strings.ToLower(strings.TrimSpace(f))
+				if !containsString(symbolFields, f) {
+					return nil, fmt.Errorf("line %d: unknown column '%s'; use %s", line, f, strings.Join(symbolFields, ", "))
+				}
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if len(rec) > len(fields) {
+			return nil, fmt.Errorf("line %d: expected at most %d columns, not %d", line, len(fields), len(rec))
+		}
+		e := watchEntry{line: line}
+		for i, v := range rec {
+			if err := e.setField(fields[i], strings.TrimSpace(v)); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		if e.symbol == "" {
+			return nil, fmt.Errorf("line %d: missing symbol", line)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Parses a JSON symbols file, a list of objects with the fields of jsonSymbol.
+func parseJSONSymbols(data []byte) ([]watchEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("line %d: expected a list of symbols", lineAt(data, 0))
+	}
+	var entries []watchEntry
+	for dec.More() {
+		line := lineAt(data, int(dec.InputOffset()))
+		var js jsonSymbol
+		if err := dec.Decode(&js); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if strings.TrimSpace(js.Symbol) == "" {
+			return nil, fmt.Errorf("line %d: missing symbol", line)
+		}
+		entries = append(entries, watchEntry{
+			symbol:     strings.TrimSpace(js.Symbol),
+			target:     strings.ToUpper(strings.TrimSpace(js.Target)),
+			note:       js.Note,
+			quantity:   js.Quantity,
+			alertAbove: js.AlertAbove,
+			alertBelow: js.AlertBelow,
+			line:       line,
+		})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("line %d: %v", lineAt(data, int(dec.InputOffset())), err)
+	}
+	return entries, nil
+}
+
+// Returns the line of the first value at or after an offset, skipping
+// spaces and commas.
+func lineAt(data []byte, off int) int {
+	for off < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[off])) {
+		off++
+	}
+	return bytes.Count(data[:off], []byte("\n")) + 1
+}
+
+// Sets a field of an entry from its text in a symbols file.
+func (e *watchEntry) setField(field, v string) error {
+	if v == "" {
+		return nil
+	}
+	switch field {
+	case "symbol":
+		e.symbol = v
+	case "target":
+		e.target = strings.ToUpper(v)
+	case "note":
+		e.note = v
+	default:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return errors.New("invalid " + strings.ReplaceAll(field, "_", " ") + " '" + v + "'")
+		}
+		switch field {
+		case "quantity":
+			e.quantity = f
+		case "alert_above":
+			e.alertAbove = f
+		case "alert_below":
+			e.alertBelow = f
+		}
+	}
+	return nil
+}
+
+// Merges entries for the same coin into the first, adding up quantities.
+// A target or alert threshold is taken from whichever entry has one, and
+// it is an error for two entries to give different ones. The first note
+// is kept.
+func mergeEntries(entries []watchEntry) ([]watchEntry, error) {
+	var out []watchEntry
+	index := make(map[string]int)
+	for _, e := range entries {
+		key := strings.ToLower(e.symbol)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(out)
+			out = append(out, e)
+			continue
+		}
+		m := &out[i]
+		var field string
+		switch {
+		case !mergeField(&m.target, e.target):
+			field = "target"
+		case !mergeField(&m.alertAbove, e.alertAbove):
+			field = "alert above"
+		case !mergeField(&m.alertBelow, e.alertBelow):
+			field = "alert below"
+		}
+		if field != "" {
+			return nil, fmt.Errorf("line %d: %s has a different %s than on line %d", e.line, e.symbol, field, m.line)
+		}
+		mergeField(&m.note, e.note)
+		m.quantity += e.quantity
+	}
+	return out, nil
+}
+
+// Sets a merged field to v if it is unset. Reports false if both are set
+// and differ.
+func mergeField[T comparable](field *T, v T) bool {
+	var zero T
+	if v == zero || *field == v {
+		return true
+	}
+	if *field == zero {
+		*field = v
+		return true
+	}
+	return false
+}
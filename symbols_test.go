@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSVSymbolsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unknown column", "symbol,amount\nbtc,1\n", "line 1: unknown column 'amount'"},
+		{"too many columns", "btc,1,usd,2,3,note,extra\n", "line 1: expected at most 6 columns, not 7"},
+		{"too many header columns", "symbol,quantity\n\nbtc,1,usd\n", "line 3: expected at most 2 columns, not 3"},
+		{"invalid quantity", "# holdings\nbtc,1\neth,lots\n", "line 3: invalid quantity 'lots'"},
+		{"invalid alert", "symbol,alert_below\nbtc,low\n", "line 2: invalid alert below 'low'"},
+		{"missing symbol", "btc,1\n,2\n", "line 2: missing symbol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCSVSymbols([]byte(tt.data))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONSymbolsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not a list", "\n{\"symbol\": \"btc\"}\n", "line 2: expected a list of symbols"},
+		{"missing symbol", "[\n  {\"symbol\": \"btc\"},\n  {\"quantity\": 2}\n]\n", "line 3: missing symbol"},
+		{"wrong type", "[\n  {\"symbol\": \"btc\"},\n\n  {\"symbol\": \"eth\", \"quantity\": \"2\"}\n]\n", "line 4: "},
+		{"unterminated", "[\n  {\"symbol\": \"btc\"}\n", "line 3: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseJSONSymbols([]byte(tt.data))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSymbolsFormat(t *testing.T) {
+	tests := []struct {
+		path string
+		data string
+		want string
+	}{
+		{"coins.csv", "btc\n", "csv"},
+		{"coins.JSON", "btc\n", "json"},
+		{"coins", "# my coins\n\n[{\"symbol\": \"btc\"}]\n", "json"},
+		{"coins", "\nbtc,1\n", "csv"},
+		{"coins", "btc usd  # a, b\neth,1\n", "text"},
+		{"-", "btc\n", "text"},
+	}
+	for _, tt := range tests {
+		if got := symbolsFormat(tt.path, []byte(tt.data)); got != tt.want {
+			t.Errorf("symbolsFormat(%q, %q) = %s, want %s", tt.path, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestMergeEntries(t *testing.T) {
+	entries := []watchEntry{
+		{symbol: "btc", quantity: 1, line: 1},
+		{symbol: "BTC", quantity: 2, target: "EUR", line: 2},
+		{symbol: "eth", alertAbove: 3000, line: 3},
+	}
+	got, err := mergeEntries(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].quantity != 3 || got[0].target != "EUR" {
+		t.Errorf("got %+v", got)
+	}
+
+	entries = append(entries, watchEntry{symbol: "eth", alertAbove: 3500, line: 4})
+	_, err = mergeEntries(entries)
+	if want := "line 4: eth has a different alert above than on line 3"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,441 @@
+// theme.go
+// Themes decide how cells are colored. Cells are painted with a role, such
+// as up or down, which the listing's theme turns into terminal colors.
+// Themes are built in or defined in themes.json in the config directory.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// colorRole is what the color of a cell means.
+type colorRole int
+
+const (
+	roleAccent colorRole = iota // symbols, pairs and ranks
+	roleLabel                   // coin names
+	roleUp                      // rising prices and good news
+	roleDown                    // falling prices
+	roleWarn                    // missing prices and warnings
+	roleInfo                    // other market data
+	roleText                    // plain text
+	roleError                   // errors
+)
+
+// roleNames maps the role names used in theme definitions to roles.
+var roleNames = map[string]colorRole{
+	"accent": roleAccent,
+	"label":  roleLabel,
+	"up":     roleUp,
+	"down":   roleDown,
+	"warn":   roleWarn,
+	"info":   roleInfo,
+	"text":   roleText,
+	"error":  roleError,
+}
+
+// paint is how a cell is colored: a role and, for moves up or down, the size
+// of the move in percent, which gradient mode scales the intensity by.
+type paint struct {
+	role colorRole
+	move float64
+}
+
+var (
+	paintAccent = paint{role: roleAccent}
+	paintLabel  = paint{role: roleLabel}
+	paintUp     = paint{role: roleUp}
+	paintDown   = paint{role: roleDown}
+	paintWarn   = paint{role: roleWarn}
+	paintInfo   = paint{role: roleInfo}
+	paintText   = paint{role: roleText}
+	paintError  = paint{role: roleError}
+)
+
+// Returns the paint for a price change in percent.
+func movePaint(pc float64) paint {
+	if pc >= 0 {
+		return paint{role: roleUp, move: pc}
+	}
+	return paint{role: roleDown, move: -pc}
+}
+
+// styleSpec defines the style of a role in a theme. Colors are basic color
+// names (e.g. blue, lightred, default), 256-color indexes (e.g. 208) or
+// truecolor hex codes (e.g. #0072b2).
+type styleSpec struct {
+	FG   string `json:"fg,omitempty"`
+	BG   string `json:"bg,omitempty"`
+	Bold bool   `json:"bold,omitempty"`
+}
+
+// builtinThemes are the themes which need no config. Roles a configured
+// theme leaves out are taken from the default theme.
+var builtinThemes = map[string]map[string]styleSpec{
+	"default": {
+		"accent": {BG: "blue"},
+		"label":  {FG: "blue"},
+		"up":     {BG: "green"},
+		"down":   {BG: "red"},
+		"warn":   {BG: "yellow"},
+		"info":   {BG: "darkgray"},
+		"text":   {FG: "default"},
+		"error":  {BG: "red"},
+	},
+	"light": {
+		"accent": {FG: "white", BG: "blue"},
+		"label":  {FG: "blue", Bold: true},
+		"up":     {FG: "black", BG: "lightgreen"},
+		"down":   {FG: "black", BG: "lightred"},
+		"warn":   {FG: "black", BG: "lightyellow"},
+		"info":   {FG: "black", BG: "252"},
+		"text":   {FG: "default"},
+		"error":  {FG: "white", BG: "red"},
+	},
+	"high-contrast": {
+		"accent": {FG: "lightwhite", BG: "blue", Bold: true},
+		"label":  {FG: "lightwhite", Bold: true},
+		"up":     {FG: "black", BG: "lightgreen", Bold: true},
+		"down":   {FG: "lightwhite", BG: "red", Bold: true},
+		"warn":   {FG: "black", BG: "lightyellow", Bold: true},
+		"info":   {FG: "lightwhite", BG: "black"},
+		"text":   {FG: "default", Bold: true},
+		"error":  {FG: "lightwhite", BG: "red", Bold: true},
+	},
+	// Okabe-Ito colors, with blue for up and orange for down.
+	"colorblind": {
+		"accent": {FG: "black", BG: "#cc79a7"},
+		"label":  {FG: "#56b4e9"},
+		"up":     {FG: "white", BG: "#0072b2"},
+		"down":   {FG: "black", BG: "#e69f00"},
+		"warn":   {FG: "black", BG: "#f0e442"},
+		"info":   {BG: "darkgray"},
+		"text":   {FG: "default"},
+		"error":  {FG: "black", BG: "#d55e00"},
+	},
+}
+
+// basicColorNames are the 16 basic terminal colors, in ANSI order.
+var basicColorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"darkgray", "lightred", "lightgreen", "lightyellow", "lightblue", "lightmagenta", "lightcyan", "lightwhite"}
+
+// basicColorRGB approximates the basic colors, for gradients and for
+// reducing other colors to basic ones.
+var basicColorRGB = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// Color depths a terminal supports.
+const (
+	depthBasic = iota
+	depth256
+	depthTrue
+)
+
+// gradientScale is the move in percent shown at full intensity in gradient
+// mode. Smaller moves are blended towards the info background.
+const gradientScale = 10.0
+
+// colorKind is how a termColor is given.
+type colorKind int
+
+const (
+	colorNone colorKind = iota
+	colorDefault
+	colorBasic
+	color256
+	colorRGB
+)
+
+// termColor is a foreground or background color.
+type termColor struct {
+	kind colorKind
+	n    int
+	rgb  [3]uint8
+}
+
+// style is the colors and weight of a role.
+type style struct {
+	fg, bg termColor
+	bold   bool
+}
+
+// theme maps roles to styles for a terminal's color depth.
+type theme struct {
+	name     string
+	styles   map[colorRole]style
+	depth    int
+	gradient bool
+}
+
+// Returns the default theme at the terminal's color depth.
+func defaultTheme() theme {
+	th, _ := newTheme("default", builtinThemes["default"])
+	return th
+}
+
+// Loads a theme by name from the config directory or the built in themes.
+func loadTheme(name string, gradient bool) (theme, error) {
+	specs, err := themeSpecs()
+	if err != nil {
+		return theme{}, err
+	}
+	roles, ok := specs[strings.ToLower(name)]
+	if !ok {
+		return theme{}, fmt.Errorf("unknown theme '%s'; use %s", name, strings.Join(themeNames(specs), ", "))
+	}
+	th, err := newTheme(name, roles)
+	th.gradient = gradient
+	return th, err
+}
+
+// Returns the built in themes with those in themes.json added. Configured
+// themes replace built in themes of the same name.
+func themeSpecs() (map[string]map[string]styleSpec, error) {
+	specs := make(map[string]map[string]styleSpec)
+	for name, roles := range builtinThemes {
+		specs[name] = roles
+	}
+	var configured map[string]map[string]styleSpec
+	if err := readConfigFile("themes.json", &configured); err != nil {
+		return nil, err
+	}
+	for name, roles := range configured {
+		specs[strings.ToLower(name)] = roles
+	}
+	return specs, nil
+}
+
+// Returns the sorted theme names.
+func themeNames(specs map[string]map[string]styleSpec) []string {
+	var names []string
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lists the theme names, painting each role in each theme.
+func listThemes(list listing) {
+	specs, err := themeSpecs()
+	if err != nil {
+		usrMessage("Could not load themes: "+err.Error()+".", true, list)
+	}
+	for _, name := range themeNames(specs) {
+		th, err := newTheme(name, specs[name])
+		if err != nil {
+			usrMessage("Theme "+name+": "+err.Error()+".", false, list)
+			continue
+		}
+		list.theme = th
+		tPrint(name, true, list, paintText, 16)
+		for _, role := range []string{"accent", "label", "up", "down", "warn", "info"} {
+			tPrint(role, true, list, paint{role: roleNames[role]}, 9)
+		}
+		fmt.Println(" ")
+	}
+	fmt.Println()
+}
+
+// Builds a theme from role styles, completing it with the default theme.
+func newTheme(name string, roles map[string]styleSpec) (theme, error) {
+	th := theme{name: name, styles: make(map[colorRole]style), depth: colorDepth()}
+	for _, defaults := range []map[string]styleSpec{builtinThemes["default"], roles} {
+		for roleName, spec := range defaults {
+			role, ok := roleNames[strings.ToLower(roleName)]
+			if !ok {
+				return th, fmt.Errorf("unknown role '%s'", roleName)
+			}
+			st, err := parseStyle(spec)
+			if err != nil {
+				return th, fmt.Errorf("role %s: %v", roleName, err)
+			}
+			th.styles[role] = st
+		}
+	}
+	return th, nil
+}
+
+// Returns the color depth of the terminal.
+func colorDepth() int {
+	if color.SupportTrueColor() {
+		return depthTrue
+	} else if color.Support256Color() {
+		return depth256
+	}
+	return depthBasic
+}
+
+// Parses a role's style.
+func parseStyle(spec styleSpec) (style, error) {
+	fg, err := parseColor(spec.FG)
+	if err != nil {
+		return style{}, err
+	}
+	bg, err := parseColor(spec.BG)
+	if err != nil {
+		return style{}, err
+	}
+	return style{fg: fg, bg: bg, bold: spec.Bold}, nil
+}
+
+// Parses a color name, 256-color index or hex code. Empty is no color.
+func parseColor(str string) (termColor, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+	switch {
+	case str == "":
+		return termColor{}, nil
+	case str == "default":
+		return termColor{kind: colorDefault}, nil
+	case strings.HasPrefix(str, "#"):
+		v, err := strconv.ParseUint(str[1:], 16, 32)
+		if err != nil || len(str) != 7 {
+			return termColor{}, fmt.Errorf("invalid hex color '%s'", str)
+		}
+		return termColor{kind: colorRGB, rgb: [3]uint8{uint8(v >> 16), uint8(v >> 8), uint8(v)}}, nil
+	}
+	if n, err := strconv.Atoi(str); err == nil {
+		if n < 0 || n > 255 {
+			return termColor{}, fmt.Errorf("color index %d is not within 0-255", n)
+		}
+		return termColor{kind: color256, n: n}, nil
+	}
+	if str == "gray" || str == "grey" {
+		str = "darkgray"
+	}
+	for n, name := range basicColorNames {
+		if str == name {
+			return termColor{kind: colorBasic, n: n}, nil
+		}
+	}
+	return termColor{}, fmt.Errorf("unknown color '%s'", str)
+}
+
+// Returns a string painted in the theme.
+func (th theme) render(p paint, str string) string {
+	if th.styles == nil {
+		th = defaultTheme()
+	}
+	st := th.styles[p.role]
+	if th.gradient && th.depth > depthBasic && (p.role == roleUp || p.role == roleDown) && st.bg.kind != colorNone {
+		st.bg = th.blend(st.bg, p.move)
+	}
+	var codes []string
+	if st.bold {
+		codes = append(codes, "1")
+	}
+	if code := st.fg.code(false, th.depth); code != "" {
+		codes = append(codes, code)
+	}
+	if code := st.bg.code(true, th.depth); code != "" {
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return str
+	}
+	return color.RenderCode(strings.Join(codes, ";"), str)
+}
+
+// Blends a color towards the info background by the size of a move, so
+// that small moves are dim and moves of gradientScale or more are full.
+func (th theme) blend(c termColor, move float64) termColor {
+	base := [3]uint8{68, 68, 68}
+	if info := th.styles[roleInfo].bg; info.kind != colorNone && info.kind != colorDefault {
+		base = info.toRGB()
+	}
+	full := c.toRGB()
+	t := 0.25 + 0.75*math.Min(math.Abs(move)/gradientScale, 1)
+	var rgb [3]uint8
+	for i := range rgb {
+		rgb[i] = uint8(math.Round(float64(base[i]) + t*(float64(full[i])-float64(base[i]))))
+	}
+	return termColor{kind: colorRGB, rgb: rgb}
+}
+
+// Returns the SGR code of a color, reduced to the color depth.
+func (c termColor) code(bg bool, depth int) string {
+	offset := 0
+	if bg {
+		offset = 10
+	}
+	kind := c.kind
+	if kind == colorRGB && depth < depthTrue {
+		c, kind = termColor{kind: color256, n: rgbTo256(c.rgb)}, color256
+	}
+	if kind == color256 && depth < depth256 {
+		c, kind = termColor{kind: colorBasic, n: rgbToBasic(c.toRGB())}, colorBasic
+	}
+	switch kind {
+	case colorDefault:
+		return strconv.Itoa(39 + offset)
+	case colorBasic:
+		if c.n < 8 {
+			return strconv.Itoa(30 + offset + c.n)
+		}
+		return strconv.Itoa(90 + offset + c.n - 8)
+	case color256:
+		return strconv.Itoa(38+offset) + ";5;" + strconv.Itoa(c.n)
+	case colorRGB:
+		return fmt.Sprintf("%d;2;%d;%d;%d", 38+offset, c.rgb[0], c.rgb[1], c.rgb[2])
+	}
+	return ""
+}
+
+// Returns the approximate RGB value of a color.
+func (c termColor) toRGB() [3]uint8 {
+	switch c.kind {
+	case colorRGB:
+		return c.rgb
+	case colorBasic:
+		return basicColorRGB[c.n]
+	case color256:
+		if c.n < 16 {
+			return basicColorRGB[c.n]
+		} else if c.n >= 232 {
+			v := uint8(8 + 10*(c.n-232))
+			return [3]uint8{v, v, v}
+		}
+		levels := [6]uint8{0, 95, 135, 175, 215, 255}
+		n := c.n - 16
+		return [3]uint8{levels[n/36], levels[n/6%6], levels[n%6]}
+	}
+	return [3]uint8{}
+}
+
+// Returns the nearest color of the 256-color cube.
+func rgbTo256(rgb [3]uint8) int {
+	var idx [3]int
+	for i, v := range rgb {
+		if v >= 115 {
+			idx[i] = (int(v) - 35) / 40
+		} else if v >= 48 {
+			idx[i] = 1
+		}
+	}
+	return 16 + 36*idx[0] + 6*idx[1] + idx[2]
+}
+
+// Returns the nearest basic color.
+func rgbToBasic(rgb [3]uint8) int {
+	best, bestDist := 0, math.MaxFloat64
+	for n, b := range basicColorRGB {
+		var dist float64
+		for i := range rgb {
+			d := float64(rgb[i]) - float64(b[i])
+			dist += d * d
+		}
+		if dist < bestDist {
+			best, bestDist = n, dist
+		}
+	}
+	return best
+}
@@ -0,0 +1,374 @@
+// watchlist.go
+// Watchlists are named lists of coins kept in the config directory, one
+// file per list, and managed with the watch command. Each line of a
+// watchlist is a coin symbol, optionally followed by a target currency and
+// by a note after a '#'. Lines starting with '#' are comments.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultWatchlist = "default"
+	watchlistDir     = "watchlists"
+	watchlistExt     = ".txt"
+	watchTargetWidth = 9
+)
+
+// watchEntry is a coin to list, with its own target currency and a note
+// if they were given. Entries from symbols files may also have a quantity
+// and alert thresholds, and know the line they were read from.
+type watchEntry struct {
+	symbol     string
+	target     string
+	note       string
+	quantity   float64
+	alertAbove float64
+	alertBelow float64
+	line       int
+}
+
+// watchLine is a line of a watchlist file: an entry, or a comment or blank
+// line which is kept as it is when the file is saved.
+type watchLine struct {
+	entry *watchEntry
+	text  string
+}
+
+// watchlist is a named watchlist and the lines of its file.
+type watchlist struct {
+	name  string
+	lines []watchLine
+}
+
+// watchlistName is the form of a watchlist name, which is also its file name.
+var watchlistName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Runs a watch subcommand: add, rm, ls or show.
+func runWatch(args []string, name, note string, list listing) {
+	if len(args) == 0 {
+		usrMessage("The watch command takes add, rm, ls or show.", true, list)
+	}
+	switch args[0] {
+	case "add":
+		watchAdd(args[1:], name, note, list)
+	case "rm":
+		watchRemove(args[1:], name, list)
+	case "ls":
+		listWatchlists(list)
+	case "show":
+		if len(args) > 2 {
+			usrMessage("The watch show command takes one watchlist name.", true, list)
+		} else if len(args) == 2 {
+			name = args[1]
+		}
+		showWatchlist(name, list)
+	default:
+		usrMessage("Unknown watch command '"+args[0]+"'; use add, rm, ls or show.", true, list)
+	}
+}
+
+// Adds coins to a watchlist, given as symbol or symbol:target. Coins are
+// saved by the symbol they resolve to, so that names given with
+// --resolve-names are saved as symbols. Coins which are already in it get
+// the new target and note, if given.
+func watchAdd(args []string, name, note string, list listing) {
+	if len(args) == 0 {
+		usrMessage("The watch add command takes one or more coin symbols.", true, list)
+	}
+	w := mustLoadWatchlist(name, list)
+	for _, arg := range args {
+		e := watchEntry{symbol: arg, note: note}
+		if i := strings.Index(arg, ":"); i >= 0 {
+			e.symbol, e.target = arg[:i], strings.ToUpper(arg[i+1:])
+		}
+		if err := e.validate(list); err != nil {
+			usrMessage(err.Error()+"; nothing was saved.", true, list)
+		}
+		e.symbol, _ = lookupCoin(e.symbol, list)
+		if i := w.find(e.symbol); i >= 0 {
+			old := w.lines[i].entry
+			if e.target != "" {
+				old.target = e.target
+			}
+			if e.note != "" {
+				old.note = e.note
+			}
+			continue
+		}
+		w.lines = append(w.lines, watchLine{entry: &e})
+	}
+	if err := w.save(list); err != nil {
+		usrMessage("Could not save watchlist "+w.name+": "+err.Error()+".", true, list)
+	}
+	fmt.Println("Watchlist " + w.name + " has " + coinCount(len(w.entries())) + ".")
+}
+
+// Removes coins from a watchlist, with every line they are on. Coins are
+// matched by symbol as given, or by the symbol they resolve to.
+func watchRemove(args []string, name string, list listing) {
+	if len(args) == 0 {
+		usrMessage("The watch rm command takes one or more coin symbols.", true, list)
+	}
+	w := mustLoadWatchlist(name, list)
+	for _, arg := range args {
+		n := w.remove(arg)
+		if symb, id := lookupCoin(arg, list); id != "" && !strings.EqualFold(symb, arg) {
+			n += w.remove(symb)
+		}
+		if n == 0 {
+			usrMessage("Coin '"+arg+"' is not in watchlist "+w.name+".", false, list)
+		}
+	}
+	if err := w.save(list); err != nil {
+		usrMessage("Could not save watchlist "+w.name+": "+err.Error()+".", true, list)
+	}
+	fmt.Println("Watchlist " + w.name + " has " + coinCount(len(w.entries())) + ".")
+}
+
+// Lists the watchlists and how many coins each has.
+func listWatchlists(list listing) {
+	dir, err := configDir()
+	if err != nil {
+		usrMessage("Could not find the config directory: "+err.Error()+".", true, list)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, watchlistDir, "*"+watchlistExt))
+	if err != nil || len(files) == 0 {
+		usrMessage("There are no watchlists yet; add coins with ccpc watch add.", false, list)
+		return
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), watchlistExt)
+		w, err := loadWatchlist(name)
+		if err != nil {
+			usrMessage(err.Error()+".", false, list)
+			continue
+		}
+		tPrint(name, true, list, paintAccent, list.nameWidth)
+		tPrint(coinCount(len(w.entries())), true, list, paintInfo, list.symbolWidth+4)
+		fmt.Println(" ")
+	}
+}
+
+// Shows the coins of a watchlist with their targets and notes.
+func showWatchlist(name string, list listing) {
+	w := mustLoadWatchlist(name, list)
+	entries := w.entries()
+	if len(entries) == 0 {
+		usrMessage("Watchlist "+w.name+" is empty.", false, list)
+		return
+	}
+	for _, e := range entries {
+		symb, id := lookupCoin(e.symbol, list)
+		tPrint(symb, true, list, paintAccent, list.symbolWidth)
+		tPrint(id, true, list, paintLabel, list.nameWidth)
+		if e.target != "" {
+			tPrint(e.target, true, list, paintInfo, watchTargetWidth)
+		} else {
+			tPrint("-", true, list, paintInfo, watchTargetWidth)
+		}
+		if e.note != "" {
+			tPrint(e.note, true, list, paintText, textWidth(e.note)+4)
+		}
+		fmt.Println(" ")
+	}
+}
+
+// Loads a watchlist, exiting with a message if it cannot be read.
+func mustLoadWatchlist(name string, list listing) watchlist {
+	w, err := loadWatchlist(name)
+	if err != nil {
+		usrMessage(err.Error()+".", true, list)
+	}
+	return w
+}
+
+// Returns the path of a watchlist file.
+func watchlistPath(name string) (string, error) {
+	if !watchlistName.MatchString(name) {
+		return "", fmt.Errorf("invalid watchlist name '%s'; use letters, digits, '-' and '_'", name)
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, watchlistDir, name+watchlistExt), nil
+}
+
+// Loads a watchlist. A watchlist without a file is empty.
+func loadWatchlist(name string) (watchlist, error) {
+	w := watchlist{name: name}
+	path, err := watchlistPath(name)
+	if err != nil {
+		return w, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return w, nil
+	} else if err != nil {
+		return w, err
+	}
+	defer file.Close()
+	w.lines, err = parseWatchLines(file)
+	if err != nil {
+		return w, fmt.Errorf("watchlist %s: %v", name, err)
+	}
+	return w, nil
+}
+
+// Parses the lines of a watchlist. Errors name the line they are on.
+func parseWatchLines(r io.Reader) ([]watchLine, error) {
+	var lines []watchLine
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		e, err := parseWatchEntry(s.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		} else if e != nil {
+			e.line = n
+		}
+		lines = append(lines, watchLine{entry: e, text: s.Text()})
+	}
+	return lines, s.Err()
+}
+
+// Parses a watchlist line, returning nil for comments and blank lines.
+func parseWatchEntry(text string) (*watchEntry, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.HasPrefix(text, "#") {
+		return nil, nil
+	}
+	e := &watchEntry{}
+	if i := strings.Index(text, "#"); i >= 0 {
+		text, e.note = text[:i], strings.TrimSpace(text[i+1:])
+	}
+	fields := strings.Fields(text)
+	switch len(fields) {
+	case 2:
+		e.target = strings.ToUpper(fields[1])
+		fallthrough
+	case 1:
+		e.symbol = fields[0]
+	default:
+		return nil, errors.New("expected a coin symbol, an optional target currency and an optional # note")
+	}
+	return e, nil
+}
+
+// Returns the entries of a watchlist, without repeated coins.
+func (w watchlist) entries() []watchEntry {
+	var entries []watchEntry
+	seen := make(map[string]bool)
+	for _, l := range w.lines {
+		if l.entry == nil || seen[strings.ToLower(l.entry.symbol)] {
+			continue
+		}
+		seen[strings.ToLower(l.entry.symbol)] = true
+		entries = append(entries, *l.entry)
+	}
+	return entries
+}
+
+// Returns the line index of a coin in a watchlist, or -1.
+func (w watchlist) find(symbol string) int {
+	for i, l := range w.lines {
+		if l.entry != nil && strings.EqualFold(l.entry.symbol, symbol) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Removes every line with a symbol, returning how many were removed.
+func (w *watchlist) remove(symbol string) int {
+	n := 0
+	for i := w.find(symbol); i >= 0; i = w.find(symbol) {
+		w.lines = append(w.lines[:i], w.lines[i+1:]...)
+		n++
+	}
+	return n
+}
+
+// Checks every entry of a watchlist and saves it, keeping its comments
+// and dropping repeated coins.
+func (w watchlist) save(list listing) error {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for n, l := range w.lines {
+		if l.entry == nil {
+			b.WriteString(l.text + "\n")
+			continue
+		}
+		if seen[strings.ToLower(l.entry.symbol)] {
+			continue
+		}
+		seen[strings.ToLower(l.entry.symbol)] = true
+		if err := l.entry.validate(list); err != nil {
+			return fmt.Errorf("line %d: %v", n+1, err)
+		}
+		b.WriteString(l.entry.String() + "\n")
+	}
+	path, err := watchlistPath(w.name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Checks that an entry's coin and target are known.
+func (e watchEntry) validate(list listing) error {
+	if _, id := lookupCoin(e.symbol, list); id == "" {
+		return errors.New(unknownSymbolMessage(e.symbol))
+	}
+	if e.target != "" && !knownCurrency(e.target) {
+		return errors.New("unknown target currency '" + e.target + "'")
+	}
+	return nil
+}
+
+// Returns an entry as a watchlist line.
+func (e watchEntry) String() string {
+	line := e.symbol
+	if e.target != "" {
+		line += " " + strings.ToLower(e.target)
+	}
+	if e.note != "" {
+		line += "  # " + e.note
+	}
+	return line
+}
+
+// Returns a number of coins, e.g. "1 coin" or "3 coins".
+func coinCount(n int) string {
+	if n == 1 {
+		return "1 coin"
+	}
+	return strconv.Itoa(n) + " coins"
+}
+
+// Returns the symbols of entries.
+func entrySymbols(entries []watchEntry) []string {
+	var symbols []string
+	for _, e := range entries {
+		symbols = append(symbols, e.symbol)
+	}
+	return symbols
+}